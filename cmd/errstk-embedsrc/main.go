@@ -0,0 +1,180 @@
+// Command errstk-embedsrc embeds a compressed snapshot of the main module's source
+// code into a binary, so that errstk can show source lines in stack traces of
+// binaries deployed without their source tree.
+//
+// Add a go:generate directive to the main package and run go generate before building:
+//
+//	//go:generate go run github.com/tomoemon/go-errstk/cmd/errstk-embedsrc
+//
+// It writes errstk_sources.zip, holding the module's non-test .go files, and
+// errstk_sources.go, which embeds the archive with go:embed and registers it
+// with errstk.RegisterEmbeddedSource.
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/mod/modfile"
+)
+
+const (
+	archiveName = "errstk_sources.zip"
+	sourceName  = "errstk_sources.go"
+)
+
+// generatedSource is the Go file that embeds and registers the archive.
+const generatedSource = `// Code generated by errstk-embedsrc. DO NOT EDIT.
+
+package %s
+
+import (
+	_ "embed"
+
+	"github.com/tomoemon/go-errstk"
+)
+
+//go:embed %s
+var errstkSources []byte
+
+func init() {
+	if err := errstk.RegisterEmbeddedSource(errstkSources); err != nil {
+		panic(err)
+	}
+}
+`
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("errstk-embedsrc: ")
+
+	root := flag.String("root", "", "module root directory (default: the directory of the nearest go.mod)")
+	out := flag.String("o", ".", "output directory")
+	pkg := flag.String("pkg", os.Getenv("GOPACKAGE"), "package name of the generated file (default: $GOPACKAGE or main)")
+	flag.Parse()
+
+	if *root == "" {
+		dir, err := findModuleRoot(".")
+		if err != nil {
+			log.Fatal(err)
+		}
+		*root = dir
+	}
+	if *pkg == "" {
+		*pkg = "main"
+	}
+
+	archive, err := buildArchive(*root)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(*out, archiveName), archive, 0o644); err != nil {
+		log.Fatal(err)
+	}
+	source := fmt.Sprintf(generatedSource, *pkg, archiveName)
+	if err := os.WriteFile(filepath.Join(*out, sourceName), []byte(source), 0o644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// findModuleRoot returns the nearest directory at or above dir that contains go.mod.
+func findModuleRoot(dir string) (string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	for {
+		if _, err := os.Stat(filepath.Join(dir, "go.mod")); err == nil {
+			return dir, nil
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", errors.New("go.mod not found")
+		}
+		dir = parent
+	}
+}
+
+// buildArchive returns a zip archive of the non-test .go files of the module at root.
+// The archive comment is the module path, entries are named by their slash-separated
+// path relative to root, and entries of package main have the comment "package main".
+// testdata and vendor directories, hidden directories and nested modules are skipped.
+func buildArchive(root string) ([]byte, error) {
+	gomod, err := os.ReadFile(filepath.Join(root, "go.mod"))
+	if err != nil {
+		return nil, err
+	}
+	modulePath := modfile.ModulePath(gomod)
+	if modulePath == "" {
+		return nil, fmt.Errorf("%s: missing module path", filepath.Join(root, "go.mod"))
+	}
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	err = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		name := d.Name()
+		if d.IsDir() {
+			if path == root {
+				return nil
+			}
+			if name == "testdata" || name == "vendor" || strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") {
+				return filepath.SkipDir
+			}
+			if _, err := os.Stat(filepath.Join(path, "go.mod")); err == nil {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !strings.HasSuffix(name, ".go") || strings.HasSuffix(name, "_test.go") || name == sourceName {
+			return nil
+		}
+
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		header := &zip.FileHeader{Name: filepath.ToSlash(rel), Method: zip.Deflate}
+		if isMainPackage(path, content) {
+			header.Comment = "package main"
+		}
+		w, err := zw.CreateHeader(header)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(content)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := zw.SetComment(modulePath); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// isMainPackage reports whether the Go file declares package main.
+func isMainPackage(path string, content []byte) bool {
+	f, err := parser.ParseFile(token.NewFileSet(), path, content, parser.PackageClauseOnly)
+	return err == nil && f.Name.Name == "main"
+}
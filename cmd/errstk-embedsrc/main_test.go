@@ -0,0 +1,64 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestBuildArchive(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		"go.mod":                  "module example.com/app\n",
+		"app.go":                  "package app\n",
+		"app_test.go":             "package app\n",
+		"errstk_sources.go":       "package app\n",
+		"README.md":               "# app\n",
+		"internal/db/db.go":       "package db\n",
+		"cmd/server/main.go":      "package main\n",
+		"testdata/fixture.go":     "package fixture\n",
+		".hidden/hidden.go":       "package hidden\n",
+		"tools/go.mod":            "module example.com/app/tools\n",
+		"tools/tools.go":          "package tools\n",
+		"vendor/example.com/x.go": "package x\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	data, err := buildArchive(root)
+	if err != nil {
+		t.Fatalf("buildArchive returned error: %v", err)
+	}
+	r, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Comment != "example.com/app" {
+		t.Errorf("archive comment = %q, want example.com/app", r.Comment)
+	}
+
+	var names, mainFiles []string
+	for _, f := range r.File {
+		names = append(names, f.Name)
+		if f.Comment == "package main" {
+			mainFiles = append(mainFiles, f.Name)
+		}
+	}
+	slices.Sort(names)
+	if want := []string{"app.go", "cmd/server/main.go", "internal/db/db.go"}; !slices.Equal(names, want) {
+		t.Errorf("archive files = %v, want %v", names, want)
+	}
+	if want := []string{"cmd/server/main.go"}; !slices.Equal(mainFiles, want) {
+		t.Errorf("main files = %v, want %v", mainFiles, want)
+	}
+}
@@ -0,0 +1,175 @@
+// Command errstk-symbolize resolves the program counters recorded by
+// errstk.NewRawStacks into function names, files and line numbers, using the
+// symbol and line tables of the binary that produced them.
+//
+// Usage:
+//
+//	errstk-symbolize -binary ./app [payload.json]
+//
+// The payload is the JSON encoding of errstk.RawStacks, read from the named file
+// or from standard input. The binary must be an unstripped ELF copy of the
+// program with the same build ID; use -force to symbolize with a different
+// build. The output has the same format as errstk.ErrorStack, so it can be read
+// back with errstk.ParseStacks.
+//
+// debug/gosym does not expand inlined calls: they are reported as part of the
+// function they were inlined into, with the file and line of the inlined code.
+// The command prints a note about this to standard error.
+package main
+
+import (
+	"debug/elf"
+	"debug/gosym"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/tomoemon/go-errstk"
+	"github.com/tomoemon/go-errstk/internal/buildid"
+)
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("errstk-symbolize: ")
+
+	binaryPath := flag.String("binary", "", "unstripped binary that produced the payload (required)")
+	force := flag.Bool("force", false, "symbolize even if the build IDs do not match")
+	flag.Parse()
+	if *binaryPath == "" || flag.NArg() > 1 {
+		fmt.Fprintln(os.Stderr, "usage: errstk-symbolize -binary path [-force] [payload.json]")
+		os.Exit(2)
+	}
+
+	var in io.Reader = os.Stdin
+	if flag.NArg() == 1 {
+		f, err := os.Open(flag.Arg(0))
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer f.Close()
+		in = f
+	}
+	var raw errstk.RawStacks
+	if err := json.NewDecoder(in).Decode(&raw); err != nil {
+		log.Fatalf("reading payload: %v", err)
+	}
+
+	b, err := openBinary(*binaryPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	out, err := b.symbolize(&raw, *force)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Print(out)
+	log.Print(inlineNote)
+}
+
+// inlineNote is printed after the output, which cannot mark inlined frames.
+const inlineNote = "note: inlined calls are not expanded; they appear as the function they were inlined into, at the line of the inlined code"
+
+// symbolTable is the symbol and line information of a binary.
+type symbolTable struct {
+	buildID string
+	table   *gosym.Table
+}
+
+// openBinary reads the build ID and the Go line table of the ELF binary at path.
+func openBinary(path string) (*symbolTable, error) {
+	f, err := elf.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	pclntab := f.Section(".gopclntab")
+	if pclntab == nil {
+		pclntab = f.Section(".data.rel.ro.gopclntab")
+	}
+	text := f.Section(".text")
+	if pclntab == nil || text == nil {
+		return nil, fmt.Errorf("%s: no Go line table; is it a stripped or non-Go binary?", path)
+	}
+	pcln, err := pclntab.Data()
+	if err != nil {
+		return nil, err
+	}
+	// Like cmd/internal/objfile, prefer runtime.text over the start of .text,
+	// which differ when the linker places other code first.
+	textStart := text.Addr
+	if syms, err := f.Symbols(); err == nil {
+		for _, s := range syms {
+			if s.Name == "runtime.text" {
+				textStart = s.Value
+				break
+			}
+		}
+	}
+	table, err := gosym.NewTable(nil, gosym.NewLineTable(pcln, textStart))
+	if err != nil {
+		return nil, err
+	}
+	return &symbolTable{buildID: elfBuildID(f), table: table}, nil
+}
+
+// elfBuildID returns the Go build ID stored in the note segments of f,
+// or an empty string.
+func elfBuildID(f *elf.File) string {
+	for _, p := range f.Progs {
+		if p.Type != elf.PT_NOTE {
+			continue
+		}
+		notes, err := io.ReadAll(p.Open())
+		if err != nil {
+			continue
+		}
+		if id := buildid.FromNotes(notes, f.ByteOrder); id != "" {
+			return id
+		}
+	}
+	return ""
+}
+
+// symbolize returns the stacks of raw in the format of errstk.ErrorStack.
+func (s *symbolTable) symbolize(raw *errstk.RawStacks, force bool) (string, error) {
+	if raw.BuildID != s.buildID && !force {
+		return "", fmt.Errorf("build ID mismatch: payload has %q, binary has %q", raw.BuildID, s.buildID)
+	}
+	anchor := s.table.LookupFunc(raw.AnchorFunc)
+	if anchor == nil {
+		return "", fmt.Errorf("anchor function %q not found in binary", raw.AnchorFunc)
+	}
+	// The process may have loaded the binary at a different address than it was
+	// linked at; the anchor gives the difference.
+	slide := uint64(raw.AnchorPC) - anchor.Entry
+
+	parts := make([]string, 0, len(raw.Stacks))
+	for _, stack := range raw.Stacks {
+		header := stack.Message
+		if stack.Label != "" {
+			header = stack.Label + ":"
+		}
+		var b strings.Builder
+		b.WriteString(header + "\n")
+		for _, pc := range stack.PCs {
+			b.WriteString(s.frame(uint64(pc) - slide))
+		}
+		parts = append(parts, b.String())
+	}
+	return strings.Join(parts, "\n"), nil
+}
+
+// frame returns the frame of the return address pc, formatted like runtime/debug.Stack.
+func (s *symbolTable) frame(pc uint64) string {
+	// pc is a return address; look up the call instruction before it.
+	file, line, fn := s.table.PCToLine(pc - 1)
+	if fn == nil {
+		return fmt.Sprintf("???()\n\t???:0 +0x%x\n", pc)
+	}
+	return fmt.Sprintf("%s()\n\t%s:%d +0x%x\n", fn.Name, file, line, pc)
+}
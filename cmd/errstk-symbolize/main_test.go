@@ -0,0 +1,123 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/tomoemon/go-errstk"
+)
+
+// fixtureLoadLine returns the line of the errstk.With call in testdata/fixture/main.go,
+// which is marked with a "// loadLine" comment.
+func fixtureLoadLine(t *testing.T) int {
+	t.Helper()
+	data, err := os.ReadFile("testdata/fixture/main.go")
+	if err != nil {
+		t.Fatalf("reading fixture: %v", err)
+	}
+	for i, line := range strings.Split(string(data), "\n") {
+		if strings.HasSuffix(line, "// loadLine") {
+			return i + 1
+		}
+	}
+	t.Fatal("fixture has no // loadLine marker")
+	return 0
+}
+
+// buildFixture builds testdata/fixture with the extra build flags, runs it, and
+// returns the path of the binary and the payload it printed.
+func buildFixture(t *testing.T, flags ...string) (string, *errstk.RawStacks) {
+	t.Helper()
+	if runtime.GOOS != "linux" {
+		t.Skip("errstk-symbolize only supports ELF binaries")
+	}
+	goTool, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go command not available")
+	}
+
+	bin := filepath.Join(t.TempDir(), "fixture")
+	args := append(append([]string{"build", "-o", bin}, flags...), "./testdata/fixture")
+	if out, err := exec.Command(goTool, args...).CombinedOutput(); err != nil {
+		t.Fatalf("go build failed: %v\n%s", err, out)
+	}
+	out, err := exec.Command(bin).Output()
+	if err != nil {
+		t.Fatalf("running fixture failed: %v", err)
+	}
+	var raw errstk.RawStacks
+	if err := json.Unmarshal(out, &raw); err != nil {
+		t.Fatalf("decoding payload: %v\n%s", err, out)
+	}
+	return bin, &raw
+}
+
+func TestSymbolize(t *testing.T) {
+	tests := []struct {
+		name  string
+		flags []string
+	}{
+		{"default", nil},
+		{"position independent", []string{"-buildmode=pie"}},
+		{"trimpath", []string{"-trimpath"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bin, raw := buildFixture(t, tt.flags...)
+			if raw.BuildID == "" {
+				t.Fatal("payload has no build ID")
+			}
+
+			table, err := openBinary(bin)
+			if err != nil {
+				t.Fatalf("openBinary returned error: %v", err)
+			}
+			if table.buildID != raw.BuildID {
+				t.Errorf("build ID = %q, payload has %q", table.buildID, raw.BuildID)
+			}
+			out, err := table.symbolize(raw, false)
+			if err != nil {
+				t.Fatalf("symbolize returned error: %v", err)
+			}
+
+			stacks := errstk.ParseStacks(out)
+			if len(stacks) != 1 {
+				t.Fatalf("ParseStacks found %d stacks, want 1:\n%s", len(stacks), out)
+			}
+			if stacks[0].Message != "not found" {
+				t.Errorf("Message = %q, want %q", stacks[0].Message, "not found")
+			}
+			var names []string
+			for _, frame := range stacks[0].Frames {
+				names = append(names, frame.FullName())
+			}
+			if want := []string{"main.load", "main.main", "runtime.main"}; len(names) < 3 || strings.Join(names[:3], ",") != strings.Join(want, ",") {
+				t.Errorf("frames = %v, want prefix %v", names, want)
+			}
+			load := stacks[0].Frames[0]
+			if line := fixtureLoadLine(t); !strings.HasSuffix(load.File, "testdata/fixture/main.go") || load.LineNumber != line {
+				t.Errorf("main.load at %s:%d, want testdata/fixture/main.go:%d", load.File, load.LineNumber, line)
+			}
+		})
+	}
+
+	t.Run("build ID mismatch", func(t *testing.T) {
+		bin, raw := buildFixture(t)
+		raw.BuildID = "other"
+		table, err := openBinary(bin)
+		if err != nil {
+			t.Fatalf("openBinary returned error: %v", err)
+		}
+		if _, err := table.symbolize(raw, false); err == nil || !strings.Contains(err.Error(), "build ID mismatch") {
+			t.Errorf("symbolize error = %v, want build ID mismatch", err)
+		}
+		if _, err := table.symbolize(raw, true); err != nil {
+			t.Errorf("symbolize with force returned error: %v", err)
+		}
+	})
+}
@@ -0,0 +1,21 @@
+// Command fixture prints the raw stacks of an error as JSON, for the errstk-symbolize tests.
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+
+	"github.com/tomoemon/go-errstk"
+)
+
+//go:noinline
+func load() error {
+	return errstk.With(errors.New("not found")) // loadLine
+}
+
+func main() {
+	if err := json.NewEncoder(os.Stdout).Encode(errstk.NewRawStacks(load())); err != nil {
+		panic(err)
+	}
+}
@@ -0,0 +1,103 @@
+package errstk
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// setElideCommonFrames changes DefaultElideCommonFrames for the duration of a test.
+func setElideCommonFrames(t *testing.T, enabled bool) {
+	t.Helper()
+	saved := DefaultElideCommonFrames
+	DefaultElideCommonFrames = enabled
+	t.Cleanup(func() {
+		DefaultElideCommonFrames = saved
+	})
+}
+
+//go:noinline
+func joinTwoStacks() error {
+	err1 := With(errors.New("error 1"))
+	err2 := With(errors.New("error 2"))
+	return errors.Join(err1, err2)
+}
+
+func TestCommonSuffixLen(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b []StackFrame
+		want int
+	}{
+		{"empty", nil, nil, 0},
+		{"one empty", testFrames("main.a", "main.main"), nil, 0},
+		{"identical", testFrames("main.a", "main.main"), testFrames("main.a", "main.main"), 2},
+		{"shared callers", testFrames("main.a", "main.b", "main.main")[1:], testFrames("main.x", "main.b", "main.main")[1:], 2},
+		{"nothing in common", testFrames("main.a"), testFrames("main.b"), 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := commonSuffixLen(tt.a, tt.b); got != tt.want {
+				t.Errorf("commonSuffixLen() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestElideCommonFrames(t *testing.T) {
+	t.Run("disabled by default", func(t *testing.T) {
+		stackTrace := ErrorStack(joinTwoStacks())
+		if strings.Contains(stackTrace, "in common with above") {
+			t.Errorf("ErrorStack should not elide frames by default, got:\n%s", stackTrace)
+		}
+	})
+
+	t.Run("errors.Join branches share their callers", func(t *testing.T) {
+		setElideCommonFrames(t, true)
+		err := joinTwoStacks()
+
+		var frames [][]StackFrame
+		WalkStack(err, func(_ error, f []StackFrame) {
+			frames = append(frames, f)
+		})
+		common := len(frames[1]) - 1 // every frame except joinTwoStacks itself
+
+		stackTrace := ErrorStack(err)
+		marker := fmt.Sprintf("... %d frames in common with above\n", common)
+		if strings.Count(stackTrace, marker) != 1 {
+			t.Fatalf("ErrorStack should contain %q once, got:\n%s", marker, stackTrace)
+		}
+		if strings.Count(stackTrace, "TestElideCommonFrames") != 1 {
+			t.Errorf("shared caller frames should be printed once, got:\n%s", stackTrace)
+		}
+
+		second := stackTrace[strings.LastIndex(stackTrace, "error 2\n"):]
+		if strings.Count(second, "joinTwoStacks") != 1 {
+			t.Errorf("second branch should keep its own frame, got:\n%s", second)
+		}
+	})
+
+	t.Run("nested errors.Join and fmt.Errorf chains", func(t *testing.T) {
+		setElideCommonFrames(t, true)
+		err := fmt.Errorf("outer: %w", errors.Join(joinTwoStacks(), fmt.Errorf("wrapped: %w", With(errors.New("error 3")))))
+
+		stackTrace := ErrorStack(err)
+		if n := strings.Count(stackTrace, "in common with above"); n != 2 {
+			t.Errorf("ErrorStack should elide frames for 2 stacks, got %d:\n%s", n, stackTrace)
+		}
+		if !strings.HasPrefix(stackTrace, "outer: error 1\nerror 2\nwrapped: error 3\n\n") {
+			t.Errorf("ErrorStack should start with the full message, got:\n%s", stackTrace)
+		}
+	})
+
+	t.Run("single stack is unchanged", func(t *testing.T) {
+		err := With(errors.New("test error"))
+		want := ErrorStack(err)
+
+		setElideCommonFrames(t, true)
+		if got := ErrorStack(err); got != want {
+			t.Errorf("ErrorStack with a single stack changed:\n%s\nwant:\n%s", got, want)
+		}
+	})
+}
@@ -0,0 +1,146 @@
+package errstk
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+	"sync"
+)
+
+// embeddedSourceMainComment is the zip file comment that marks files of package main.
+const embeddedSourceMainComment = "package main"
+
+// embeddedSources is the source snapshot registered by RegisterEmbeddedSource.
+var embeddedSources embeddedSourceStore
+
+// RegisterEmbeddedSource registers a snapshot of the main module's source code,
+// so that SourceLine, SourceContext and source context output work in binaries
+// deployed without their source tree. It is normally called from the file
+// generated by the errstk-embedsrc command:
+//
+//	//go:generate go run github.com/tomoemon/go-errstk/cmd/errstk-embedsrc
+//
+// data is a zip archive whose comment is the module path and whose entries are the
+// module's .go files, named by their path relative to the module root. Files of
+// package main carry the entry comment "package main".
+//
+// The snapshot is only used for frames of packages in that module and of package main;
+// frames of other packages are read from the file system as before.
+// Registering a new snapshot replaces the previous one.
+// Each file stays compressed until its source is first looked up.
+func RegisterEmbeddedSource(data []byte) error {
+	r, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return fmt.Errorf("errstk: reading embedded source: %w", err)
+	}
+	if r.Comment == "" {
+		return errors.New("errstk: reading embedded source: missing module path")
+	}
+
+	files := make(map[string]*embeddedFile, len(r.File))
+	var mainFiles []string
+	for _, f := range r.File {
+		files[f.Name] = &embeddedFile{zip: f}
+		if f.Comment == embeddedSourceMainComment {
+			mainFiles = append(mainFiles, f.Name)
+		}
+	}
+
+	embeddedSources.set(r.Comment, files, mainFiles)
+	return nil
+}
+
+// embeddedFile is a file of the snapshot. It stays compressed until its lines
+// are first needed.
+type embeddedFile struct {
+	zip *zip.File
+
+	once  sync.Once
+	split []string
+	err   error
+}
+
+// lines returns the lines of the file, decompressing it on first use.
+func (f *embeddedFile) lines() ([]string, error) {
+	f.once.Do(func() {
+		f.split, f.err = readZipLines(f.zip)
+		if f.err != nil {
+			f.err = fmt.Errorf("errstk: reading embedded source %s: %w", f.zip.Name, f.err)
+		}
+	})
+	return f.split, f.err
+}
+
+// readZipLines returns the lines of a file in a zip archive.
+func readZipLines(f *zip.File) ([]string, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	var lines []string
+	scanner := bufio.NewScanner(rc)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	return lines, scanner.Err()
+}
+
+// embeddedSourceStore holds the registered source snapshot.
+type embeddedSourceStore struct {
+	mu        sync.RWMutex
+	module    string
+	files     map[string]*embeddedFile
+	mainFiles []string
+}
+
+func (s *embeddedSourceStore) set(module string, files map[string]*embeddedFile, mainFiles []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.module, s.files, s.mainFiles = module, files, mainFiles
+}
+
+// file returns the embedded file of frame, or nil if the frame is not in the snapshot.
+func (s *embeddedSourceStore) file(frame *StackFrame) *embeddedFile {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.files == nil {
+		return nil
+	}
+
+	file := strings.ReplaceAll(frame.File, "\\", "/")
+	switch {
+	case frame.Package == s.module:
+		return s.files[path.Base(file)]
+	case strings.HasPrefix(frame.Package, s.module+"/"):
+		name := strings.TrimPrefix(frame.Package, s.module+"/") + "/" + path.Base(file)
+		return s.files[name]
+	case frame.Package == "main":
+		// The directory of package main is not part of its name;
+		// find the longest matching path suffix among the main files.
+		best := ""
+		for _, name := range s.mainFiles {
+			if (file == name || strings.HasSuffix(file, "/"+name)) && len(name) > len(best) {
+				best = name
+			}
+		}
+		if best != "" {
+			return s.files[best]
+		}
+	}
+	return nil
+}
+
+// sourceLines returns the lines of the source file of frame,
+// from the embedded snapshot if it has the file, or from the file system.
+func (frame *StackFrame) sourceLines() ([]string, error) {
+	if f := embeddedSources.file(frame); f != nil {
+		return f.lines()
+	}
+	return sourceFiles.lines(frame.File)
+}
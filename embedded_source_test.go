@@ -0,0 +1,128 @@
+package errstk
+
+import (
+	"archive/zip"
+	"bytes"
+	"testing"
+)
+
+// sourceArchive builds an archive in the format read by RegisterEmbeddedSource.
+// Files whose name is in mainFiles are marked as package main.
+func sourceArchive(t *testing.T, module string, files map[string]string, mainFiles ...string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range files {
+		header := &zip.FileHeader{Name: name, Method: zip.Deflate}
+		for _, m := range mainFiles {
+			if m == name {
+				header.Comment = embeddedSourceMainComment
+			}
+		}
+		w, err := zw.CreateHeader(header)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.SetComment(module); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// registerEmbeddedSource registers data for the duration of a test.
+func registerEmbeddedSource(t *testing.T, data []byte) {
+	t.Helper()
+	if err := RegisterEmbeddedSource(data); err != nil {
+		t.Fatalf("RegisterEmbeddedSource returned error: %v", err)
+	}
+	t.Cleanup(func() {
+		embeddedSources.set("", nil, nil)
+	})
+}
+
+func TestRegisterEmbeddedSource(t *testing.T) {
+	t.Run("invalid archives", func(t *testing.T) {
+		if err := RegisterEmbeddedSource([]byte("not a zip")); err == nil {
+			t.Error("RegisterEmbeddedSource should reject invalid data")
+		}
+		if err := RegisterEmbeddedSource(sourceArchive(t, "", nil)); err == nil {
+			t.Error("RegisterEmbeddedSource should reject an archive without module path")
+		}
+	})
+
+	registerEmbeddedSource(t, sourceArchive(t, "example.com/app", map[string]string{
+		"app.go":             "package app\n\nfunc Root() {}\n",
+		"internal/db/db.go":  "package db\n\nfunc Query() {}\n",
+		"cmd/server/main.go": "package main\n\nfunc main() {}\n",
+		"main.go":            "package main\n\nfunc rootMain() {}\n",
+	}, "cmd/server/main.go", "main.go"))
+
+	t.Run("files are decompressed on first lookup", func(t *testing.T) {
+		if embeddedSources.files["app.go"].split != nil {
+			t.Fatal("app.go should not be decompressed before it is looked up")
+		}
+		frame := StackFrame{Package: "example.com/app", File: "/build/app.go", LineNumber: 1}
+		if _, err := frame.SourceLine(); err != nil {
+			t.Fatalf("SourceLine returned error: %v", err)
+		}
+		if embeddedSources.files["app.go"].split == nil {
+			t.Error("app.go should be decompressed after it is looked up")
+		}
+		if embeddedSources.files["internal/db/db.go"].split != nil {
+			t.Error("files that were not looked up should stay compressed")
+		}
+	})
+
+	tests := []struct {
+		name  string
+		frame StackFrame
+		want  string
+	}{
+		{"module root package", StackFrame{Package: "example.com/app", File: "/build/app.go", LineNumber: 3}, "func Root() {}"},
+		{"nested package", StackFrame{Package: "example.com/app/internal/db", File: "/build/internal/db/db.go", LineNumber: 3}, "func Query() {}"},
+		{"trimmed path", StackFrame{Package: "example.com/app/internal/db", File: "example.com/app/internal/db/db.go", LineNumber: 1}, "package db"},
+		{"package main by longest suffix", StackFrame{Package: "main", File: "/build/cmd/server/main.go", LineNumber: 3}, "func main() {}"},
+		{"package main at the root", StackFrame{Package: "main", File: "/build/main.go", LineNumber: 3}, "func rootMain() {}"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			line, err := tt.frame.SourceLine()
+			if err != nil {
+				t.Fatalf("SourceLine returned error: %v", err)
+			}
+			if line != tt.want {
+				t.Errorf("SourceLine() = %q, want %q", line, tt.want)
+			}
+		})
+	}
+
+	t.Run("other packages are read from the file system", func(t *testing.T) {
+		frame := StackFrame{Package: "example.com/other/internal/db", File: "/build/internal/db/db.go", LineNumber: 3}
+		if _, err := frame.SourceLine(); err == nil {
+			t.Error("SourceLine should not use the snapshot for other modules")
+		}
+
+		frame = StackFrame{Package: "github.com/tomoemon/go-errstk", File: "embedded_source_test.go", LineNumber: 1}
+		if line, err := frame.SourceLine(); err != nil || line != "package errstk" {
+			t.Errorf("SourceLine() = %q, %v, want package errstk", line, err)
+		}
+	})
+
+	t.Run("SourceContext uses the snapshot", func(t *testing.T) {
+		frame := StackFrame{Package: "example.com/app", File: "/build/app.go", LineNumber: 3}
+		context, err := frame.SourceContext(2, 2)
+		if err != nil {
+			t.Fatalf("SourceContext returned error: %v", err)
+		}
+		if len(context) != 3 || !context[2].Current {
+			t.Errorf("SourceContext() = %v, want 3 lines ending with the current line", context)
+		}
+	})
+}
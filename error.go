@@ -5,7 +5,9 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"slices"
 	"strings"
+	"sync"
 )
 
 // DefaultMaxStackDepth is the maximum number of stack frames to capture on any error.
@@ -18,6 +20,14 @@ var DefaultMaxStackDepth = 32
 // Advanced users can set this at package initialization time if needed.
 var DefaultSkipFrames = 0
 
+// DefaultElideCommonFrames controls whether ErrorStack prints frames shared between stacks only once.
+// When enabled, each stack trace in the output omits the trailing frames it has in common with
+// the stack printed right before it, and ends with a "... N frames in common with above" line instead,
+// like Java's "... N more". This shortens the output for errors.Join of errors captured in the same function.
+// Typically this should remain false.
+// Advanced users can set this at package initialization time if needed.
+var DefaultElideCommonFrames = false
+
 // DefaultStackFrameFormatter is the default function used to format stack frames.
 // By default, it formats frames in the same way as runtime/debug.Stack().
 // Advanced users can replace this with a custom formatter at package initialization time.
@@ -40,6 +50,7 @@ var DefaultStackFrameFormatter stackFrameFormatter = defaultStackFrameFormatter
 // Does nothing if *errp is nil.
 // Avoids double-wrapping if the error already has a stack trace.
 // When used with defer, captures the stack trace at the return point.
+// Optional key/value pairs are attached as structured fields; see With.
 //
 // Example:
 //
@@ -52,11 +63,39 @@ var DefaultStackFrameFormatter stackFrameFormatter = defaultStackFrameFormatter
 //	}
 //
 //go:noinline
-func Wrap(errp *error) {
+func Wrap(errp *error, keyvals ...any) {
 	if *errp != nil {
 		// Skip 4 frames: Wrap -> innerWithStack -> callers -> runtime.Callers
 		const innerSkip = 4
-		*errp = innerWithStack(*errp, DefaultSkipFrames+innerSkip)
+		opts := newCaptureOptions(nil)
+		opts.fields = newFields(keyvals)
+		*errp = innerWithStack(*errp, innerSkip, opts)
+	}
+}
+
+// Wrapf prefixes the error pointed to by errp with a formatted message and
+// wraps it with a stack trace, in the same way as Wrap.
+// The result is equivalent to fmt.Errorf(format+": %w", args..., *errp),
+// so errors.Is and errors.As still see the original error.
+//
+// Does nothing if *errp is nil.
+// The message is always added, but the stack trace is not captured again
+// if the error already has one.
+//
+// Example:
+//
+//	func loadUser(id string) (err error) {
+//	    defer errstk.Wrapf(&err, "load user %s", id)
+//	    ...
+//	}
+//
+//go:noinline
+func Wrapf(errp *error, format string, args ...any) {
+	if *errp != nil {
+		// Skip 4 frames: Wrapf -> innerWithStack -> callers -> runtime.Callers
+		const innerSkip = 4
+		err := fmt.Errorf("%s: %w", fmt.Sprintf(format, args...), *errp)
+		*errp = innerWithStack(err, innerSkip, newCaptureOptions(nil))
 	}
 }
 
@@ -66,77 +105,129 @@ func Wrap(errp *error) {
 // Avoids double-wrapping if the error already has a stack trace.
 // Preserves the error chain for errors.Is and errors.As.
 //
+// Optional key/value pairs are attached to the error as structured fields,
+// in the same alternating form as log/slog (a Field value may also be passed directly).
+// Fields are attached even if the error already has a stack trace.
+// Use Fields to collect them from the error chain.
+//
 // Example:
 //
 //	err := doSomething()
 //	if err != nil {
-//	    return errstk.With(err)
+//	    return errstk.With(err, "user_id", userID)
 //	}
 //
 //go:noinline
-func With(err error) error {
+func With(err error, keyvals ...any) error {
 	// Skip 4 frames: With -> innerWithStack -> callers -> runtime.Callers
 	const innerSkip = 4
-	return innerWithStack(err, DefaultSkipFrames+innerSkip)
+	opts := newCaptureOptions(nil)
+	opts.fields = newFields(keyvals)
+	return innerWithStack(err, innerSkip, opts)
 }
 
+// innerWithStack wraps err with a stack trace.
+// innerSkip is the number of frames between the public entry point and runtime.Callers;
+// opts.skip is added on top of it.
+//
 //go:noinline
-func innerWithStack(err error, skip int) error {
+func innerWithStack(err error, innerSkip int, opts captureOptions) error {
 	if err == nil {
 		return nil
 	}
-	var stackError *withStack
-	if errors.As(err, &stackError) {
-		return err
+	if !opts.force {
+		if HasStack(err) {
+			if len(opts.fields) > 0 {
+				return &withFields{error: err, fields: opts.fields}
+			}
+			return err
+		}
+	}
+	ws := &withStack{
+		error:  err,
+		stack:  callers(opts.skip+innerSkip, opts.depth),
+		kind:   opts.kind,
+		fields: opts.fields,
 	}
-	return &withStack{
-		err,
-		callers(skip, DefaultMaxStackDepth),
+	if opts.kind == kindOrigin {
+		return linkSpawn(ws, opts)
 	}
+	return ws
 }
 
 type withStack struct {
 	error
-	stack []uintptr
+	stack  []uintptr
+	kind   captureKind
+	fields []Field
+
+	// frames memoizes the resolved stack frames; see stackFrames.
+	framesOnce sync.Once
+	frames     []StackFrame
 }
 
 func (w *withStack) Format(s fmt.State, verb rune) {
+	formatError(s, verb, w)
+}
+
+// formatError implements fmt.Formatter for the error types of this package:
+//
+//   - %s, %v: the error message
+//   - %+v: the message and stack traces, as returned by ErrorStack
+//   - %#v: the error chain, as returned by ErrorTree
+//   - %q: the quoted error message
+func formatError(s fmt.State, verb rune, err error) {
 	switch verb {
 	case 'v':
 		if s.Flag('+') {
-			_, _ = io.WriteString(s, w.ErrorStack())
+			_, _ = io.WriteString(s, ErrorStack(err))
+			return
+		}
+		if s.Flag('#') {
+			_, _ = io.WriteString(s, ErrorTree(err))
 			return
 		}
 		fallthrough
 	case 's':
-		_, _ = io.WriteString(s, w.Error())
+		_, _ = io.WriteString(s, err.Error())
 	case 'q':
-		_, _ = fmt.Fprintf(s, "%q", w.Error())
+		_, _ = fmt.Fprintf(s, "%q", err.Error())
 	}
 }
 
 // Stack returns the callstack formatted the same way that go does
 // in runtime/debug.Stack()
 func (w *withStack) Stack() []byte {
-	return formatStackFrames(w.StackFrames())
+	return formatStackFrames(w.stackFrames())
 }
 
 // StackFrames returns the stack frames captured when this error was wrapped.
 // Each StackFrame contains information about the file, line number, and function name.
+// Frames are resolved once and cached; the returned slice is a copy that callers may modify.
 func (w *withStack) StackFrames() []StackFrame {
-	return stackFramesFromPC(w.stack)
+	return slices.Clone(w.stackFrames())
+}
+
+// stackFrames returns the memoized stack frames. The result must not be modified.
+func (w *withStack) stackFrames() []StackFrame {
+	w.framesOnce.Do(func() {
+		w.frames = stackFramesFromPC(w.stack)
+	})
+	return w.frames
 }
 
-// Callers satisfies the bugsnag ErrorWithCallerS() interface
-// so that the stack can be read out.
+// Callers satisfies the StackTracer interface and the bugsnag ErrorWithCallerS()
+// interface so that the stack can be read out.
 func (w *withStack) Callers() []uintptr {
 	return w.stack
 }
 
 // ErrorStack returns a string that contains both the
 // error message and the callstack.
+// If the error carries more than one stack (see Rethrow and ForceNewStack),
+// all of them are included, in the same way as the package-level ErrorStack.
 func (w *withStack) ErrorStack() string {
-	return w.Error() + "\n" + string(w.Stack())
+	return ErrorStack(w)
 }
 
 // Unwrap provides compatibility for Go 1.13 error chains.
@@ -153,6 +244,9 @@ func (w *withStack) Unwrap() error {
 // the full context. This avoids losing wrapper messages while preventing duplication
 // when the error itself has a stack trace.
 //
+// If DefaultElideCommonFrames is enabled, frames shared with the previously printed
+// stack are replaced with a "... N frames in common with above" line.
+//
 // If no stack trace is found in the error chain, returns the error message from Error().
 // Returns an empty string if err is nil.
 //
@@ -165,21 +259,84 @@ func ErrorStack(originalErr error) string {
 		return ""
 	}
 
-	var accum []string
+	type entry struct {
+		header string
+		body   string
+	}
+	var accum []entry
 	var wrapped bool
+	var prevFrames []StackFrame
 
 	WalkStack(originalErr, func(err error, frames []StackFrame) {
-		wrapped = originalErr != err
-		accum = append(accum, fmt.Sprintf("%s\n%s", err.Error(), string(formatStackFrames(frames))))
+		// Hand-off stacks are reported after the stacks they wrap, so the last
+		// callback may be for originalErr itself even if the chain is wrapped.
+		if err != originalErr {
+			wrapped = true
+		}
+		header := err.Error()
+		if label := captureLabel(err); label != "" {
+			header = label + ":"
+			// A hand-off stack is the only stack of an error that had none,
+			// so name that error above it.
+			if inner := unwrapHandOff(err); inner != nil && !hasFrames(inner) {
+				header = inner.Error() + "\n" + header
+			}
+		}
+		body := formatErrorStackFrames(frames)
+		if DefaultElideCommonFrames {
+			if n := commonSuffixLen(frames, prevFrames); n > 0 {
+				body = formatErrorStackFrames(frames[:len(frames)-n]) + formatCommonFrames(n)
+			}
+			prevFrames = frames
+		}
+		accum = append(accum, entry{header, body})
 	})
 
-	if len(accum) == 0 {
-		return originalErr.Error()
+	if len(accum) == 0 || wrapped {
+		accum = append([]entry{{header: originalErr.Error()}}, accum...)
+	}
+	// Fields collected from the whole chain are shown once, below the first message
+	if fields := Fields(originalErr); len(fields) > 0 {
+		accum[0].header += "\n" + formatFields(fields)
 	}
 	if wrapped {
-		accum = append([]string{originalErr.Error() + "\n"}, accum...)
+		// Separate the full message from the stacks with a blank line
+		accum[0].header += "\n"
 	}
-	return strings.Join(accum, "\n")
+
+	parts := make([]string, len(accum))
+	for i, e := range accum {
+		if e.body == "" {
+			parts[i] = e.header
+		} else {
+			parts[i] = e.header + "\n" + e.body
+		}
+	}
+	return strings.Join(parts, "\n")
+}
+
+// unwrapHandOff returns the error wrapped by a hand-off stack, for both
+// *withStack and decoded errors, or nil if there is not exactly one.
+func unwrapHandOff(err error) error {
+	switch u := err.(type) {
+	case interface{ Unwrap() error }:
+		return u.Unwrap()
+	case interface{ Unwrap() []error }:
+		if errs := u.Unwrap(); len(errs) == 1 {
+			return errs[0]
+		}
+	}
+	return nil
+}
+
+// hasFrames reports whether WalkStack finds any stack in the chain of err.
+func hasFrames(err error) bool {
+	found := false
+	walkChain(err, func(e chainError) bool {
+		_, found = e.frames()
+		return !found
+	})
+	return found
 }
 
 // WalkStack walks through the error chain and calls f for each error that has a stack trace.
@@ -190,6 +347,10 @@ func ErrorStack(originalErr error) string {
 //   - err: the error that contains the stack trace
 //   - frames: the stack frames captured at the point where the error was wrapped
 //
+// Stacks are reported outermost first, except for stacks attached by Rethrow:
+// those are reported after the stacks of the error they wrap, so that capture
+// points are visited in the order the error passed through them.
+//
 // WalkStack is useful when you need custom formatting or processing of error stack traces.
 // For standard formatted output, use ErrorStack() instead.
 //
@@ -217,34 +378,121 @@ func ErrorStack(originalErr error) string {
 //	})
 //	json.Marshal(traces)
 func WalkStack(err error, f func(error, []StackFrame)) {
+	walkChain(err, func(e chainError) bool {
+		if frames, ok := e.frames(); ok {
+			f(e.err, frames)
+		}
+		return true
+	})
+}
+
+// chainError is an error visited by walkChain.
+type chainError struct {
+	err error
+	// stack is the stack carried by err itself, as found by DefaultStackExtractors.
+	stack Stack
+	// hasStack reports whether an extractor found a stack.
+	hasStack bool
+	// label is the capture label of a hand-off stack, or empty; see captureLabel.
+	label string
+}
+
+func newChainError(err error) chainError {
+	stack, ok := extractStack(err)
+	return chainError{err: err, stack: stack, hasStack: ok, label: captureLabel(err)}
+}
+
+// walkChain calls yield for err and every error in its chain, following both
+// errors.Unwrap and Unwrap() []error depth first, and outer errors before the
+// errors they wrap. An error carrying a hand-off stack (see Rethrow, Group and Go)
+// is visited after the errors it wraps instead, because its stack was captured
+// after theirs. This is the order of WalkStack, ErrorStack and the other
+// functions that visit the chain.
+// Walking stops as soon as yield returns false, and walkChain then returns false.
+func walkChain(err error, yield func(chainError) bool) bool {
 	if err == nil {
-		return
+		return true
 	}
-	// Check if this error has stack trace information
-	if caller, ok := err.(interface{ Callers() []uintptr }); ok {
-		f(err, stackFramesFromPC(caller.Callers()))
+	e := newChainError(err)
+	if e.label == "" && !yield(e) {
+		return false
 	}
-	// Handle errors.Join (multiple wrapped errors)
 	if u, ok := err.(interface{ Unwrap() []error }); ok {
-		errs := u.Unwrap()
-		for _, e := range errs {
-			WalkStack(e, f)
+		for _, err := range u.Unwrap() {
+			if !walkChain(err, yield) {
+				return false
+			}
 		}
-	} else if u := errors.Unwrap(err); u != nil {
-		// Handle standard error wrapping (single wrapped error)
-		WalkStack(u, f)
+	} else if !walkChain(errors.Unwrap(err), yield) {
+		return false
+	}
+	if e.label != "" {
+		return yield(e)
 	}
+	return true
+}
+
+// frames returns the stack frames carried by the error itself (not its chain).
+// Errors providing resolved frames via StackFrames() are preferred over
+// errors whose raw program counters are found by DefaultStackExtractors.
+// A nil result from StackFrames() means the error carries no stack.
+func (e chainError) frames() ([]StackFrame, bool) {
+	if framer, ok := e.err.(interface{ StackFrames() []StackFrame }); ok {
+		if frames := framer.StackFrames(); frames != nil {
+			return frames, true
+		}
+	}
+	if e.hasStack {
+		return stackFramesFromPC(e.stack), true
+	}
+	return nil, false
+}
+
+// stackFramesOf returns the stack frames carried by err itself (not its chain).
+// See chainError.frames.
+func stackFramesOf(err error) ([]StackFrame, bool) {
+	return newChainError(err).frames()
 }
 
 func stackFramesFromPC(stack []uintptr) []StackFrame {
 	if stack == nil {
 		return nil
 	}
-	frames := make([]StackFrame, len(stack))
-	for i, pc := range stack {
-		frames[i] = newStackFrame(pc)
+	frames := make([]StackFrame, 0, len(stack))
+	for _, pc := range stack {
+		if frame := frameCache.lookup(pc); !isRunSpawnedFrame(frame) {
+			frames = append(frames, frame)
+		}
+	}
+	return DefaultFrameFilter.Apply(frames)
+}
+
+// commonSuffixLen returns the number of trailing frames a and b have in common.
+func commonSuffixLen(a, b []StackFrame) int {
+	n := 0
+	for n < len(a) && n < len(b) && a[len(a)-1-n] == b[len(b)-1-n] {
+		n++
+	}
+	return n
+}
+
+// formatCommonFrames returns the line that replaces n frames elided by DefaultElideCommonFrames.
+func formatCommonFrames(n int) string {
+	if n == 1 {
+		return "... 1 frame in common with above\n"
+	}
+	return fmt.Sprintf("... %d frames in common with above\n", n)
+}
+
+// formatErrorStackFrames returns frames formatted for ErrorStack,
+// followed by their source context if DefaultSourceContextLines is set.
+func formatErrorStackFrames(frames []StackFrame) string {
+	var b strings.Builder
+	for i := range frames {
+		b.WriteString(frames[i].String())
+		b.WriteString(formatSourceContext(&frames[i]))
 	}
-	return frames
+	return b.String()
 }
 
 // formatStackFrames returns the callstack formatted the same way that go does
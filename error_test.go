@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"slices"
 	"strings"
 	"testing"
 )
@@ -361,6 +362,57 @@ func TestWrap(t *testing.T) {
 	})
 }
 
+func TestWrapf(t *testing.T) {
+	t.Run("nil error returns nil", func(t *testing.T) {
+		f := func() (err error) {
+			defer Wrapf(&err, "load user %s", "alice")
+			return nil
+		}
+		if err := f(); err != nil {
+			t.Errorf("Wrapf(nil) = %v, want nil", err)
+		}
+	})
+
+	t.Run("prefixes message and captures stack at the return point", func(t *testing.T) {
+		baseErr := errors.New("not found")
+		f := func(id string) (err error) {
+			defer Wrapf(&err, "load user %s", id)
+			return baseErr
+		}
+
+		err := f("alice")
+		if got, want := err.Error(), "load user alice: not found"; got != want {
+			t.Errorf("Error() = %q, want %q", got, want)
+		}
+		if !errors.Is(err, baseErr) {
+			t.Error("Should preserve original error in chain")
+		}
+		frames := err.(*withStack).StackFrames()
+		if !strings.HasPrefix(frames[0].Name, "TestWrapf") {
+			t.Errorf("first frame = %q, want the deferring function", frames[0].Name)
+		}
+	})
+
+	t.Run("adds message without double wrapping", func(t *testing.T) {
+		original := With(errors.New("original error"))
+		f := func() (err error) {
+			defer Wrapf(&err, "step %d", 2)
+			return original
+		}
+
+		err := f()
+		if got, want := err.Error(), "step 2: original error"; got != want {
+			t.Errorf("Error() = %q, want %q", got, want)
+		}
+		if _, ok := err.(*withStack); ok {
+			t.Error("Should not add a second stack trace")
+		}
+		if stack, _ := StackOf(err); !slices.Equal(stack, original.(*withStack).Callers()) {
+			t.Error("Should keep the original stack trace")
+		}
+	})
+}
+
 func TestWrapWithVariableRedeclaration(t *testing.T) {
 	t.Run("short variable declaration reuses named return value", func(t *testing.T) {
 		// This is the common pattern - short declaration reuses the named return value
@@ -9,6 +9,7 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
+	"strconv"
 	"strings"
 
 	"golang.org/x/tools/go/analysis"
@@ -30,6 +31,8 @@ Example of correct usage:
 		// function implementation
 	}
 
+errstk.WrapWithOptions(&err, ...) and errstk.Wrapf(&err, format, ...) are accepted as well.
+
 The analyzer will report functions that:
 - Return error (or multiple values including error)
 - Do not have a defer statement calling errstk.Wrap with the error variable
@@ -97,7 +100,7 @@ func run(pass *analysis.Pass) (interface{}, error) {
 	ignoredRanges := make(map[string][]ignoredRange)
 	for _, f := range pass.Files {
 		filename := pass.Fset.Position(f.Pos()).Filename
-		ignoredRanges[filename] = parseNolintDirectives(f, pass.Fset)
+		ignoredRanges[filename] = parseNolintDirectives(f, pass.Fset, pass.Analyzer.Name)
 	}
 
 	inspect := pass.ResultOf[inspect.Analyzer].(*inspector.Inspector)
@@ -247,8 +250,8 @@ func isDeferErrStkWrap(deferStmt *ast.DeferStmt, errorVar string) bool {
 		return false
 	}
 
-	// Check if method name is "Wrap"
-	if callExpr.Sel.Name != "Wrap" {
+	// Check if method name is "Wrap" or one of its variants
+	if !isWrapFuncName(callExpr.Sel.Name) {
 		return false
 	}
 
@@ -289,6 +292,16 @@ func isDeferErrStkWrap(deferStmt *ast.DeferStmt, errorVar string) bool {
 	return argIdent.Name == errorVar
 }
 
+// isWrapFuncName reports whether name is an errstk function that captures
+// the stack of *errp when deferred.
+func isWrapFuncName(name string) bool {
+	switch name {
+	case "Wrap", "WrapWithOptions", "Wrapf":
+		return true
+	}
+	return false
+}
+
 // parseExcludeFlag parses the comma-separated exclude flag
 func parseExcludeFlag(flag string) []string {
 	if flag == "" {
@@ -406,8 +419,8 @@ var (
 	lintIgnorePattern = regexp.MustCompile(`^lint:(ignore|file-ignore)\s+(\S+)(?:\s+(.+))?$`)
 )
 
-// parseNolintDirectives parses nolint and lint:ignore directives from file comments
-func parseNolintDirectives(file *ast.File, fset *token.FileSet) []ignoredRange {
+// parseNolintDirectives parses nolint and lint:ignore directives for linter from file comments
+func parseNolintDirectives(file *ast.File, fset *token.FileSet, linter string) []ignoredRange {
 	var ranges []ignoredRange
 	fileStart := fset.Position(file.Pos()).Line
 	fileEnd := fset.Position(file.End()).Line
@@ -420,7 +433,7 @@ func parseNolintDirectives(file *ast.File, fset *token.FileSet) []ignoredRange {
 			// Check for nolint directive
 			if matches := nolintPattern.FindStringSubmatch(text); matches != nil {
 				linters := matches[1]
-				if shouldIgnoreLinter(linters) {
+				if shouldIgnoreLinter(linters, linter) {
 					commentLine := fset.Position(c.Pos()).Line
 					ranges = append(ranges, createIgnoredRange(commentLine, cg, file, fset, fileStart, fileEnd))
 				}
@@ -432,7 +445,7 @@ func parseNolintDirectives(file *ast.File, fset *token.FileSet) []ignoredRange {
 				directiveType := matches[1] // "ignore" or "file-ignore"
 				checkName := matches[2]
 
-				if checkName == "errstklint" {
+				if checkName == linter {
 					commentLine := fset.Position(c.Pos()).Line
 					if directiveType == "file-ignore" {
 						// File-level ignore: ignore entire file
@@ -450,13 +463,13 @@ func parseNolintDirectives(file *ast.File, fset *token.FileSet) []ignoredRange {
 	return ranges
 }
 
-// shouldIgnoreLinter checks if the linter list includes "errstklint" or "all"
-func shouldIgnoreLinter(linters string) bool {
+// shouldIgnoreLinter checks if the linter list includes linter or "all"
+func shouldIgnoreLinter(linters, linter string) bool {
 	if linters == "" || linters == "all" {
 		return true
 	}
 	for _, l := range strings.Split(linters, ",") {
-		if strings.TrimSpace(l) == "errstklint" {
+		if strings.TrimSpace(l) == linter {
 			return true
 		}
 	}
@@ -617,14 +630,22 @@ func buildDeferTextEdit(funcDecl *ast.FuncDecl, errorVarName string, pass *analy
 	}
 }
 
+// errstkImportPath is the import path of the errstk package.
+const errstkImportPath = "github.com/tomoemon/go-errstk"
+
 // hasErrstkImport checks if the file already imports errstk.
 func hasErrstkImport(file *ast.File) bool {
+	return findImport(file, errstkImportPath) != nil
+}
+
+// findImport returns the import spec of path in file, or nil if it is not imported.
+func findImport(file *ast.File, path string) *ast.ImportSpec {
 	for _, imp := range file.Imports {
-		if imp.Path.Value == `"github.com/tomoemon/go-errstk"` {
-			return true
+		if imp.Path.Value == strconv.Quote(path) {
+			return imp
 		}
 	}
-	return false
+	return nil
 }
 
 // buildImportTextEdit returns a TextEdit to add the errstk import, or nil if already imported.
@@ -632,7 +653,12 @@ func buildImportTextEdit(file *ast.File) *analysis.TextEdit {
 	if hasErrstkImport(file) {
 		return nil
 	}
+	edit := buildAddImportsTextEdit(file, errstkImportPath)
+	return &edit
+}
 
+// buildAddImportsTextEdit returns a TextEdit that adds imports of paths to file.
+func buildAddImportsTextEdit(file *ast.File, paths ...string) analysis.TextEdit {
 	for _, decl := range file.Decls {
 		gd, ok := decl.(*ast.GenDecl)
 		if !ok || gd.Tok != token.IMPORT {
@@ -640,25 +666,38 @@ func buildImportTextEdit(file *ast.File) *analysis.TextEdit {
 		}
 		if gd.Lparen.IsValid() {
 			// Grouped import: insert before closing paren
-			return &analysis.TextEdit{
+			var text strings.Builder
+			for _, path := range paths {
+				text.WriteString("\t" + strconv.Quote(path) + "\n")
+			}
+			return analysis.TextEdit{
 				Pos:     gd.Rparen,
 				End:     gd.Rparen,
-				NewText: []byte("\t\"github.com/tomoemon/go-errstk\"\n"),
+				NewText: []byte(text.String()),
 			}
 		}
 		// Single-line import: insert after the import decl
-		return &analysis.TextEdit{
+		var text strings.Builder
+		for _, path := range paths {
+			text.WriteString("\nimport " + strconv.Quote(path))
+		}
+		return analysis.TextEdit{
 			Pos:     gd.End(),
 			End:     gd.End(),
-			NewText: []byte("\nimport \"github.com/tomoemon/go-errstk\""),
+			NewText: []byte(text.String()),
 		}
 	}
 
 	// No imports at all: insert after package name
-	return &analysis.TextEdit{
+	var text strings.Builder
+	text.WriteString("\n")
+	for _, path := range paths {
+		text.WriteString("\nimport " + strconv.Quote(path))
+	}
+	return analysis.TextEdit{
 		Pos:     file.Name.End(),
 		End:     file.Name.End(),
-		NewText: []byte("\n\nimport \"github.com/tomoemon/go-errstk\""),
+		NewText: []byte(text.String()),
 	}
 }
 
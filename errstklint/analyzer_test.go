@@ -180,3 +180,13 @@ func TestMatchDoubleStarPattern(t *testing.T) {
 		})
 	}
 }
+
+func TestMigrateAnalyzerWithSuggestedFixes(t *testing.T) {
+	testdata := analysistest.TestData()
+	analysistest.RunWithSuggestedFixes(t, testdata, MigrateAnalyzer, "migrate")
+}
+
+func TestMigrateAnalyzerManual(t *testing.T) {
+	testdata := analysistest.TestData()
+	analysistest.Run(t, testdata, MigrateAnalyzer, "migratemanual", "migrateignored")
+}
@@ -0,0 +1,606 @@
+package errstklint
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/tomoemon/go-errstk/internal/importpath"
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/inspect"
+	"golang.org/x/tools/go/ast/inspector"
+)
+
+const MigrateDoc = `suggests fixes that migrate from github.com/pkg/errors to errstk
+
+This analyzer reports every use of github.com/pkg/errors and suggests
+a replacement based on errstk and the standard library:
+
+	errors.New("msg")            -> errstk.With(errors.New("msg"))
+	errors.Errorf(format, args)  -> errstk.With(fmt.Errorf(format, args))
+	errors.Wrap(err, "msg")      -> fmt.Errorf("msg: %w", err)
+	errors.Wrapf(err, "f", args) -> fmt.Errorf("f: %w", args, err)
+	errors.WithMessage(f)        -> like Wrap and Wrapf
+	errors.WithStack(err)        -> errstk.With(err)
+	errors.Cause(err) == target  -> errors.Is(err, target)
+	cause := errors.Cause(err)   -> an errors.Unwrap loop
+	errors.Is, errors.As, errors.Unwrap -> the standard library functions
+
+errors.New and errors.Errorf outside functions, typically sentinel errors,
+are replaced without errstk.With. The import of github.com/pkg/errors is
+replaced with the standard library "errors" package, and imports of fmt and
+errstk are added as needed.
+
+pkg/errors.Wrap returns nil for a nil error, but fmt.Errorf does not, so
+Wrap, Wrapf, WithMessage and WithMessagef are only rewritten inside an
+"if err != nil" block for the wrapped error. Uses that cannot be converted
+safely are reported for manual migration. A file is only fixed when all
+of its uses can be converted, so that it never imports both packages.
+
+Run it with -fix to apply the fixes:
+
+	errstkmigrate -fix ./...
+
+Use //nolint:errstkmigrate or //lint:ignore errstkmigrate to exclude
+functions or files, as with errstklint.
+`
+
+// pkgErrorsPath is the import path of github.com/pkg/errors.
+const pkgErrorsPath = "github.com/pkg/errors"
+
+// MigrateAnalyzer suggests fixes that replace github.com/pkg/errors with errstk
+// and the standard library.
+var MigrateAnalyzer = &analysis.Analyzer{
+	Name:     "errstkmigrate",
+	Doc:      MigrateDoc,
+	Run:      runMigrate,
+	Requires: []*analysis.Analyzer{inspect.Analyzer},
+}
+
+// pkgErrorsRef is a use of a github.com/pkg/errors identifier.
+type pkgErrorsRef struct {
+	sel   *ast.SelectorExpr
+	stack []ast.Node // ancestors of sel, outermost first
+}
+
+// migration is the conversion of one use of github.com/pkg/errors.
+type migration struct {
+	pos token.Pos
+	// name is the qualified name of the pkg/errors identifier, e.g. "pkg/errors.Wrap".
+	name string
+	// message describes the replacement.
+	message string
+	edits   []analysis.TextEdit
+	// manual is the reason the use must be migrated by hand, or empty.
+	manual string
+}
+
+// migrationFile holds the names used by the replacements in one file.
+type migrationFile struct {
+	pass            *analysis.Pass
+	file            *ast.File
+	errorsName      string // the name of the standard library errors package
+	fmtName         string
+	errstkName      string
+	needErrors      bool
+	needFmt         bool
+	needErrstk      bool
+	pkgErrorsImport *ast.ImportSpec // the import of github.com/pkg/errors
+}
+
+func runMigrate(pass *analysis.Pass) (interface{}, error) {
+	ignoredRanges := make(map[string][]ignoredRange)
+	for _, f := range pass.Files {
+		filename := pass.Fset.Position(f.Pos()).Filename
+		ignoredRanges[filename] = parseNolintDirectives(f, pass.Fset, pass.Analyzer.Name)
+	}
+
+	inspect := pass.ResultOf[inspect.Analyzer].(*inspector.Inspector)
+
+	var files []*ast.File
+	refs := make(map[*ast.File][]pkgErrorsRef)
+	nodeFilter := []ast.Node{
+		(*ast.SelectorExpr)(nil),
+	}
+	inspect.WithStack(nodeFilter, func(n ast.Node, push bool, stack []ast.Node) bool {
+		if !push {
+			return true
+		}
+		sel := n.(*ast.SelectorExpr)
+		if !isPkgErrorsIdent(pass, sel) {
+			return true
+		}
+		file := stack[0].(*ast.File)
+		if _, ok := refs[file]; !ok {
+			files = append(files, file)
+		}
+		refs[file] = append(refs[file], pkgErrorsRef{sel: sel, stack: slices.Clone(stack[:len(stack)-1])})
+		return true
+	})
+
+	for _, file := range files {
+		filename := pass.Fset.Position(file.Pos()).Filename
+		if isPositionIgnored(pass.Fset.Position(file.Package), ignoredRanges[filename]) {
+			continue
+		}
+		reportMigrations(pass, file, refs[file], ignoredRanges[filename])
+	}
+
+	return nil, nil
+}
+
+// isPkgErrorsIdent reports whether sel refers to an identifier of github.com/pkg/errors.
+func isPkgErrorsIdent(pass *analysis.Pass, sel *ast.SelectorExpr) bool {
+	ident, ok := sel.X.(*ast.Ident)
+	if !ok {
+		return false
+	}
+	pkgName, ok := pass.TypesInfo.Uses[ident].(*types.PkgName)
+	return ok && pkgName.Imported().Path() == pkgErrorsPath
+}
+
+// reportMigrations reports the uses of github.com/pkg/errors in file. The uses get
+// suggested fixes only if all of them in the file can be converted.
+func reportMigrations(pass *analysis.Pass, file *ast.File, refs []pkgErrorsRef, ignored []ignoredRange) {
+	mf := newMigrationFile(pass, file)
+
+	migrations := make([]migration, 0, len(refs))
+	convertible := true
+	for _, ref := range refs {
+		m := mf.migrate(ref)
+		migrations = append(migrations, m)
+		// Ignored uses keep the file from being fixed as well,
+		// since its import cannot be replaced while they remain.
+		if m.manual != "" || isPositionIgnored(pass.Fset.Position(m.pos), ignored) {
+			convertible = false
+		}
+	}
+	importEdits := mf.importEdits()
+
+	for _, m := range migrations {
+		if isPositionIgnored(pass.Fset.Position(m.pos), ignored) {
+			continue
+		}
+		if m.manual != "" {
+			pass.Report(analysis.Diagnostic{
+				Pos:     m.pos,
+				Message: m.name + " needs manual migration: " + m.manual,
+			})
+			continue
+		}
+		if !convertible {
+			pass.Report(analysis.Diagnostic{
+				Pos:     m.pos,
+				Message: m.message + " (auto-fix unavailable: other uses of pkg/errors in this file need manual migration)",
+			})
+			continue
+		}
+		pass.Report(analysis.Diagnostic{
+			Pos:     m.pos,
+			Message: m.message,
+			SuggestedFixes: []analysis.SuggestedFix{{
+				Message:   "Migrate from pkg/errors",
+				TextEdits: append(m.edits, importEdits...),
+			}},
+		})
+	}
+}
+
+// newMigrationFile returns the names to use for the replacements in file.
+func newMigrationFile(pass *analysis.Pass, file *ast.File) *migrationFile {
+	mf := &migrationFile{
+		pass:            pass,
+		file:            file,
+		fmtName:         "fmt",
+		errstkName:      "errstk",
+		pkgErrorsImport: findImport(file, pkgErrorsPath),
+	}
+	if imp := findImport(file, "errors"); imp != nil {
+		mf.errorsName = importName(imp, "errors")
+	} else {
+		// The import of pkg/errors is turned into the standard library one,
+		// so uses of it keep their name.
+		mf.errorsName = importName(mf.pkgErrorsImport, "errors")
+	}
+	if imp := findImport(file, "fmt"); imp != nil {
+		mf.fmtName = importName(imp, "fmt")
+	}
+	if imp := findImport(file, errstkImportPath); imp != nil {
+		mf.errstkName = importName(imp, "errstk")
+	}
+	return mf
+}
+
+// importName returns the name under which imp is imported, or def if it has no name.
+func importName(imp *ast.ImportSpec, def string) string {
+	if imp != nil && imp.Name != nil {
+		return imp.Name.Name
+	}
+	return def
+}
+
+// importEdits returns the edits that replace the import of pkg/errors with the
+// standard library errors package and add the imports used by the replacements.
+// Standard library imports are added next to the other standard library imports.
+func (mf *migrationFile) importEdits() []analysis.TextEdit {
+	spec := mf.pkgErrorsImport
+	var imports []*ast.ImportSpec
+	if mf.needErrors && findImport(mf.file, "errors") == nil {
+		imports = append(imports, &ast.ImportSpec{Name: spec.Name, Path: &ast.BasicLit{Value: `"errors"`}})
+	}
+	if mf.needFmt && findImport(mf.file, "fmt") == nil {
+		imports = append(imports, &ast.ImportSpec{Path: &ast.BasicLit{Value: `"fmt"`}})
+	}
+	var errstkImport *ast.ImportSpec
+	if mf.needErrstk && !hasErrstkImport(mf.file) {
+		errstkImport = &ast.ImportSpec{Path: &ast.BasicLit{Value: strconv.Quote(errstkImportPath)}}
+	}
+
+	decl := mf.importDeclOf(spec)
+	if !decl.Lparen.IsValid() {
+		// Replace the single import of pkg/errors with the new imports.
+		if errstkImport != nil {
+			imports = append(imports, errstkImport)
+		}
+		if len(imports) == 0 {
+			return []analysis.TextEdit{mf.deleteLinesEdit(decl)}
+		}
+		lines := make([]string, len(imports))
+		for i, imp := range imports {
+			lines[i] = "import " + importSpecText(imp)
+		}
+		return []analysis.TextEdit{{Pos: decl.Pos(), End: decl.End(), NewText: []byte(strings.Join(lines, "\n"))}}
+	}
+
+	edits := []analysis.TextEdit{mf.deleteLinesEdit(spec)}
+	if len(imports) > 0 {
+		edits = append(edits, mf.addStdImportsEdit(decl, imports))
+	}
+	if errstkImport != nil {
+		edits = append(edits, analysis.TextEdit{
+			Pos:     decl.Rparen,
+			End:     decl.Rparen,
+			NewText: []byte("\t" + importSpecText(errstkImport) + "\n"),
+		})
+	}
+	return edits
+}
+
+// importDeclOf returns the import declaration that contains spec.
+func (mf *migrationFile) importDeclOf(spec *ast.ImportSpec) *ast.GenDecl {
+	for _, decl := range mf.file.Decls {
+		if gd, ok := decl.(*ast.GenDecl); ok && gd.Tok == token.IMPORT && slices.Contains(gd.Specs, ast.Spec(spec)) {
+			return gd
+		}
+	}
+	return nil
+}
+
+// importSpecText returns the source code of an import spec.
+func importSpecText(imp *ast.ImportSpec) string {
+	if imp.Name != nil {
+		return imp.Name.Name + " " + imp.Path.Value
+	}
+	return imp.Path.Value
+}
+
+// addStdImportsEdit returns a TextEdit that adds the standard library imports to decl,
+// after its last standard library import or in a new group at its start.
+func (mf *migrationFile) addStdImportsEdit(decl *ast.GenDecl, imports []*ast.ImportSpec) analysis.TextEdit {
+	var text strings.Builder
+	for _, imp := range imports {
+		text.WriteString("\n\t" + importSpecText(imp))
+	}
+
+	var last *ast.ImportSpec
+	for _, spec := range decl.Specs {
+		imp := spec.(*ast.ImportSpec)
+		path, err := strconv.Unquote(imp.Path.Value)
+		if err == nil && imp != mf.pkgErrorsImport && importpath.IsStandard(path) {
+			last = imp
+		}
+	}
+	if last != nil {
+		return analysis.TextEdit{Pos: last.End(), End: last.End(), NewText: []byte(text.String())}
+	}
+	text.WriteString("\n")
+	return analysis.TextEdit{Pos: decl.Lparen + 1, End: decl.Lparen + 1, NewText: []byte(text.String())}
+}
+
+// deleteLinesEdit returns a TextEdit that deletes the lines of node.
+func (mf *migrationFile) deleteLinesEdit(node ast.Node) analysis.TextEdit {
+	tf := mf.pass.Fset.File(node.Pos())
+	start := tf.LineStart(tf.Line(node.Pos()))
+	end := node.End()
+	if line := tf.Line(end); line < tf.LineCount() {
+		end = tf.LineStart(line + 1)
+	}
+	return analysis.TextEdit{Pos: start, End: end}
+}
+
+// migrate returns the conversion of one use of github.com/pkg/errors.
+func (mf *migrationFile) migrate(ref pkgErrorsRef) migration {
+	name := ref.sel.Sel.Name
+	m := migration{pos: ref.sel.Pos(), name: "pkg/errors." + name}
+	m.message = m.name
+
+	var call *ast.CallExpr
+	if parent, ok := ref.stack[len(ref.stack)-1].(*ast.CallExpr); ok && parent.Fun == ref.sel {
+		call = parent
+	}
+	if call == nil {
+		switch _, isFunc := mf.pass.TypesInfo.Uses[ref.sel.Sel].(*types.Func); {
+		case name == "Is" || name == "As" || name == "Unwrap":
+			m.message += " can be replaced with errors." + name
+			mf.needErrors = true
+		case isFunc:
+			m.manual = "it is not called"
+		default:
+			m.manual = "it has no equivalent in errstk or the standard library"
+		}
+		return m
+	}
+
+	inFunc := isInFunc(ref.stack)
+	switch name {
+	case "New":
+		m.message += " can be replaced with errors.New"
+		mf.needErrors = true
+		m.edits = append(m.edits, mf.replaceFunEdits(call, mf.errorsName+".New")...)
+		if inFunc {
+			m.message += " and errstk.With"
+			m.edits = append(m.edits, mf.withEdits(call)...)
+		}
+	case "Errorf":
+		m.message += " can be replaced with fmt.Errorf"
+		mf.needFmt = true
+		m.edits = append(m.edits, mf.replaceFunEdits(call, mf.fmtName+".Errorf")...)
+		if inFunc {
+			m.message += " and errstk.With"
+			m.edits = append(m.edits, mf.withEdits(call)...)
+		}
+	case "WithStack":
+		m.message += " can be replaced with errstk.With"
+		mf.needErrstk = true
+		m.edits = append(m.edits, mf.replaceFunEdits(call, mf.errstkName+".With")...)
+	case "Wrap", "WithMessage":
+		m.message += " can be replaced with fmt.Errorf and %w"
+		if reason := mf.wrapReason(call, ref.stack); reason != "" {
+			m.manual = reason
+			return m
+		}
+		mf.needFmt = true
+		m.edits = append(m.edits, mf.wrapEdit(call))
+	case "Wrapf", "WithMessagef":
+		m.message += " can be replaced with fmt.Errorf and %w"
+		if reason := mf.wrapReason(call, ref.stack); reason != "" {
+			m.manual = reason
+			return m
+		}
+		if _, ok := stringLiteral(call.Args[1]); !ok {
+			m.manual = "the format is not a string literal"
+			return m
+		}
+		if call.Ellipsis.IsValid() {
+			m.manual = "the arguments are passed with ..."
+			return m
+		}
+		mf.needFmt = true
+		m.edits = append(m.edits, mf.wrapfEdit(call))
+	case "Cause":
+		return mf.migrateCause(m, call, ref.stack)
+	case "Is", "As", "Unwrap":
+		m.message += " can be replaced with errors." + name
+		mf.needErrors = true
+		m.edits = append(m.edits, mf.replaceFunEdits(call, mf.errorsName+"."+name)...)
+	default:
+		m.manual = "it has no equivalent in errstk or the standard library"
+	}
+	return m
+}
+
+// migrateCause converts a call of errors.Cause that is compared with another error,
+// or assigned to a variable.
+func (mf *migrationFile) migrateCause(m migration, call *ast.CallExpr, stack []ast.Node) migration {
+	if len(call.Args) != 1 {
+		m.manual = "unexpected arguments"
+		return m
+	}
+	arg := mf.text(call.Args[0])
+	mf.needErrors = true
+
+	switch parent := stack[len(stack)-2].(type) {
+	case *ast.BinaryExpr:
+		if parent.Op != token.EQL && parent.Op != token.NEQ {
+			break
+		}
+		other := parent.Y
+		if parent.Y == call {
+			other = parent.X
+		}
+		text := fmt.Sprintf("%s.Is(%s, %s)", mf.errorsName, arg, mf.text(other))
+		if parent.Op == token.NEQ {
+			text = "!" + text
+		}
+		m.message += " comparison can be replaced with errors.Is"
+		m.edits = append(m.edits, analysis.TextEdit{Pos: parent.Pos(), End: parent.End(), NewText: []byte(text)})
+		return m
+	case *ast.AssignStmt:
+		if len(parent.Lhs) != 1 || len(parent.Rhs) != 1 || !isStmtListParent(stack[len(stack)-3]) {
+			break
+		}
+		lhs, ok := parent.Lhs[0].(*ast.Ident)
+		if !ok || lhs.Name == "_" {
+			break
+		}
+		if parent.Tok != token.DEFINE && parent.Tok != token.ASSIGN {
+			break
+		}
+		indent := mf.indent(parent)
+		var b strings.Builder
+		fmt.Fprintf(&b, "%s %s %s\n", lhs.Name, parent.Tok, arg)
+		fmt.Fprintf(&b, "%sfor %s.Unwrap(%s) != nil {\n", indent, mf.errorsName, lhs.Name)
+		fmt.Fprintf(&b, "%s\t%s = %s.Unwrap(%s)\n", indent, lhs.Name, mf.errorsName, lhs.Name)
+		fmt.Fprintf(&b, "%s}", indent)
+		m.message += " can be replaced with an errors.Unwrap loop"
+		m.edits = append(m.edits, analysis.TextEdit{Pos: parent.Pos(), End: parent.End(), NewText: []byte(b.String())})
+		return m
+	}
+	m.manual = "only comparisons and assignments of errors.Cause are converted; consider errors.Is or errors.As"
+	return m
+}
+
+// wrapReason returns why a call of Wrap or a similar function cannot be converted,
+// or an empty string if it can.
+func (mf *migrationFile) wrapReason(call *ast.CallExpr, stack []ast.Node) string {
+	if len(call.Args) < 2 {
+		return "unexpected arguments"
+	}
+	if !isNilChecked(mf.pass, call.Args[0], stack) {
+		return "the error may be nil, and fmt.Errorf does not return nil for a nil error like pkg/errors does; " +
+			"check it with \"if err != nil\" first"
+	}
+	return ""
+}
+
+// replaceFunEdits returns the TextEdits that replace the function of call with fun.
+func (mf *migrationFile) replaceFunEdits(call *ast.CallExpr, fun string) []analysis.TextEdit {
+	if mf.text(call.Fun) == fun {
+		return nil
+	}
+	return []analysis.TextEdit{{Pos: call.Fun.Pos(), End: call.Fun.End(), NewText: []byte(fun)}}
+}
+
+// withEdits returns the TextEdits that wrap call with errstk.With.
+func (mf *migrationFile) withEdits(call *ast.CallExpr) []analysis.TextEdit {
+	mf.needErrstk = true
+	return []analysis.TextEdit{
+		{Pos: call.Pos(), End: call.Pos(), NewText: []byte(mf.errstkName + ".With(")},
+		{Pos: call.End(), End: call.End(), NewText: []byte(")")},
+	}
+}
+
+// wrapEdit returns a TextEdit that replaces errors.Wrap(err, msg) with fmt.Errorf.
+func (mf *migrationFile) wrapEdit(call *ast.CallExpr) analysis.TextEdit {
+	var text string
+	if msg, ok := stringLiteral(call.Args[1]); ok {
+		format := strconv.Quote(strings.ReplaceAll(msg, "%", "%%") + ": %w")
+		text = fmt.Sprintf("%s.Errorf(%s, %s)", mf.fmtName, format, mf.text(call.Args[0]))
+	} else {
+		text = fmt.Sprintf("%s.Errorf(\"%%s: %%w\", %s, %s)", mf.fmtName, mf.text(call.Args[1]), mf.text(call.Args[0]))
+	}
+	return analysis.TextEdit{Pos: call.Pos(), End: call.End(), NewText: []byte(text)}
+}
+
+// wrapfEdit returns a TextEdit that replaces errors.Wrapf(err, format, args...) with fmt.Errorf.
+func (mf *migrationFile) wrapfEdit(call *ast.CallExpr) analysis.TextEdit {
+	format, _ := stringLiteral(call.Args[1])
+	args := []string{strconv.Quote(format + ": %w")}
+	for _, arg := range call.Args[2:] {
+		args = append(args, mf.text(arg))
+	}
+	args = append(args, mf.text(call.Args[0]))
+	text := fmt.Sprintf("%s.Errorf(%s)", mf.fmtName, strings.Join(args, ", "))
+	return analysis.TextEdit{Pos: call.Pos(), End: call.End(), NewText: []byte(text)}
+}
+
+// text returns the source code of node.
+func (mf *migrationFile) text(node ast.Node) string {
+	return sourceText(mf.pass, node.Pos(), node.End())
+}
+
+// indent returns the whitespace before node on its line.
+func (mf *migrationFile) indent(node ast.Node) string {
+	tf := mf.pass.Fset.File(node.Pos())
+	start := tf.LineStart(tf.Line(node.Pos()))
+	return sourceText(mf.pass, start, node.Pos())
+}
+
+// stringLiteral returns the value of expr if it is a string literal.
+func stringLiteral(expr ast.Expr) (string, bool) {
+	lit, ok := expr.(*ast.BasicLit)
+	if !ok || lit.Kind != token.STRING {
+		return "", false
+	}
+	s, err := strconv.Unquote(lit.Value)
+	return s, err == nil
+}
+
+// isInFunc reports whether the innermost enclosing declaration in stack is a function.
+func isInFunc(stack []ast.Node) bool {
+	for _, n := range stack {
+		switch n.(type) {
+		case *ast.FuncDecl, *ast.FuncLit:
+			return true
+		}
+	}
+	return false
+}
+
+// isStmtListParent reports whether n holds a list of statements.
+func isStmtListParent(n ast.Node) bool {
+	switch n.(type) {
+	case *ast.BlockStmt, *ast.CaseClause, *ast.CommClause:
+		return true
+	}
+	return false
+}
+
+// isNilChecked reports whether expr is a variable and the call at the end of stack
+// is inside the body of an "if v != nil" statement for it, in the same function.
+func isNilChecked(pass *analysis.Pass, expr ast.Expr, stack []ast.Node) bool {
+	ident, ok := expr.(*ast.Ident)
+	if !ok {
+		return false
+	}
+	obj := pass.TypesInfo.Uses[ident]
+	if obj == nil {
+		return false
+	}
+	for i := len(stack) - 1; i > 0; i-- {
+		switch n := stack[i-1].(type) {
+		case *ast.FuncDecl, *ast.FuncLit:
+			return false
+		case *ast.IfStmt:
+			if n.Body == stack[i] && condChecksNotNil(pass, n.Cond, obj) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// condChecksNotNil reports whether cond, or one of the operands of a && chain,
+// is "v != nil" for the variable obj.
+func condChecksNotNil(pass *analysis.Pass, cond ast.Expr, obj types.Object) bool {
+	bin, ok := ast.Unparen(cond).(*ast.BinaryExpr)
+	if !ok {
+		return false
+	}
+	switch bin.Op {
+	case token.LAND:
+		return condChecksNotNil(pass, bin.X, obj) || condChecksNotNil(pass, bin.Y, obj)
+	case token.NEQ:
+		x, y := ast.Unparen(bin.X), ast.Unparen(bin.Y)
+		if isNilIdent(pass, x) {
+			x, y = y, x
+		}
+		ident, ok := x.(*ast.Ident)
+		return ok && isNilIdent(pass, y) && pass.TypesInfo.Uses[ident] == obj
+	}
+	return false
+}
+
+// isNilIdent reports whether expr is the predeclared nil.
+func isNilIdent(pass *analysis.Pass, expr ast.Expr) bool {
+	ident, ok := expr.(*ast.Ident)
+	if !ok {
+		return false
+	}
+	_, isNil := pass.TypesInfo.Uses[ident].(*types.Nil)
+	return isNil
+}
@@ -7,6 +7,7 @@ import (
 
 func init() {
 	register.Plugin("errstklint", New)
+	register.Plugin("errstkmigrate", NewMigrate)
 }
 
 // ErrstklintPlugin is the plugin implementation for golangci-lint
@@ -36,3 +37,22 @@ func (p *ErrstklintPlugin) BuildAnalyzers() ([]*analysis.Analyzer, error) {
 func (p *ErrstklintPlugin) GetLoadMode() string {
 	return register.LoadModeTypesInfo
 }
+
+// MigratePlugin is the plugin implementation of errstkmigrate for golangci-lint
+type MigratePlugin struct{}
+
+// NewMigrate returns the errstkmigrate plugin instance for golangci-lint plugin system.
+// errstkmigrate has no settings.
+func NewMigrate(settings any) (register.LinterPlugin, error) {
+	return &MigratePlugin{}, nil
+}
+
+// BuildAnalyzers returns the analyzers for this plugin
+func (p *MigratePlugin) BuildAnalyzers() ([]*analysis.Analyzer, error) {
+	return []*analysis.Analyzer{MigrateAnalyzer}, nil
+}
+
+// GetLoadMode returns the load mode for this plugin
+func (p *MigratePlugin) GetLoadMode() string {
+	return register.LoadModeTypesInfo
+}
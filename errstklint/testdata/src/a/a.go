@@ -14,6 +14,18 @@ func GoodMultipleReturns() (result string, err error) {
 	return "ok", nil
 }
 
+// Good: has defer errstk.WrapWithOptions(&err)
+func GoodWrapWithOptions() (err error) {
+	defer errstk.WrapWithOptions(&err)
+	return nil
+}
+
+// Good: has defer errstk.Wrapf(&err, ...)
+func GoodWrapf(id string) (err error) {
+	defer errstk.Wrapf(&err, "load user %s", id)
+	return nil
+}
+
 // Good: no error return, so no need for defer
 func NoErrorReturn() string {
 	return "ok"
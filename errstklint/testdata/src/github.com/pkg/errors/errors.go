@@ -0,0 +1,42 @@
+// This is a mock package for testing purposes only.
+// It provides the API of github.com/pkg/errors needed for the migration analyzer tests.
+package errors
+
+// Frame is a mock type for testing.
+type Frame uintptr
+
+// StackTrace is a mock type for testing.
+type StackTrace []Frame
+
+// New is a mock function for testing.
+func New(message string) error { return nil }
+
+// Errorf is a mock function for testing.
+func Errorf(format string, args ...interface{}) error { return nil }
+
+// WithStack is a mock function for testing.
+func WithStack(err error) error { return err }
+
+// Wrap is a mock function for testing.
+func Wrap(err error, message string) error { return err }
+
+// Wrapf is a mock function for testing.
+func Wrapf(err error, format string, args ...interface{}) error { return err }
+
+// WithMessage is a mock function for testing.
+func WithMessage(err error, message string) error { return err }
+
+// WithMessagef is a mock function for testing.
+func WithMessagef(err error, format string, args ...interface{}) error { return err }
+
+// Cause is a mock function for testing.
+func Cause(err error) error { return err }
+
+// Is is a mock function for testing.
+func Is(err, target error) bool { return false }
+
+// As is a mock function for testing.
+func As(err error, target interface{}) bool { return false }
+
+// Unwrap is a mock function for testing.
+func Unwrap(err error) error { return nil }
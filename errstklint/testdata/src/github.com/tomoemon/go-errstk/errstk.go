@@ -7,3 +7,21 @@ package errstk
 func Wrap(err *error) {
 	// This is a stub for testing
 }
+
+// Option is a mock type for testing.
+type Option func()
+
+// WrapWithOptions is a mock function for testing.
+func WrapWithOptions(err *error, opts ...Option) {
+	// This is a stub for testing
+}
+
+// Wrapf is a mock function for testing.
+func Wrapf(err *error, format string, args ...any) {
+	// This is a stub for testing
+}
+
+// With is a mock function for testing.
+func With(err error, keyvals ...any) error {
+	return err
+}
@@ -0,0 +1,21 @@
+package migrate
+
+import (
+	stderrors "errors"
+
+	pkgerrors "github.com/pkg/errors"
+	"github.com/tomoemon/go-errstk"
+)
+
+var errClosed = stderrors.New("closed")
+
+func Closed() error {
+	return pkgerrors.New("closed") // want `pkg/errors.New can be replaced with errors.New and errstk.With`
+}
+
+func Unwrap(err error) error {
+	if err == errClosed {
+		return errstk.With(err)
+	}
+	return pkgerrors.Unwrap(err) // want `pkg/errors.Unwrap can be replaced with errors.Unwrap`
+}
@@ -0,0 +1,53 @@
+package migrate
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+)
+
+type User struct{ ID string }
+
+func find(id string) (*User, error) {
+	return nil, nil
+}
+
+func New(id string) error {
+	return errors.New("not found: " + id) // want `pkg/errors.New can be replaced with errors.New and errstk.With`
+}
+
+func Errorf(id string) error {
+	return errors.Errorf("user %s not found", id) // want `pkg/errors.Errorf can be replaced with fmt.Errorf and errstk.With`
+}
+
+func Wrap(id string) (*User, error) {
+	user, err := find(id)
+	if err != nil {
+		return nil, errors.Wrap(err, "find 100% of users") // want `pkg/errors.Wrap can be replaced with fmt.Errorf and %w`
+	}
+	return user, nil
+}
+
+func Wrapf(id string) error {
+	if _, err := find(id); err != nil && id != "" {
+		return errors.Wrapf(err, "find user %s", id) // want `pkg/errors.Wrapf can be replaced with fmt.Errorf and %w`
+	}
+	return nil
+}
+
+func WithMessage(id, msg string) error {
+	_, err := find(id)
+	if nil != err {
+		return errors.WithMessage(err, msg) // want `pkg/errors.WithMessage can be replaced with fmt.Errorf and %w`
+	}
+	return nil
+}
+
+func WithStack(id string) error {
+	_, err := find(id)
+	return errors.WithStack(err) // want `pkg/errors.WithStack can be replaced with errstk.With`
+}
+
+func Print(err error) {
+	fmt.Println(err)
+}
@@ -0,0 +1,30 @@
+package migrate
+
+import "github.com/pkg/errors"
+
+var ErrNotFound = errors.New("not found") // want `pkg/errors.New can be replaced with errors.New`
+
+func IsNotFound(err error) bool {
+	return errors.Cause(err) == ErrNotFound // want `pkg/errors.Cause comparison can be replaced with errors.Is`
+}
+
+func IsOther(err error) bool {
+	return ErrNotFound != errors.Cause(err) // want `pkg/errors.Cause comparison can be replaced with errors.Is`
+}
+
+func Root(err error) error {
+	if err != nil {
+		cause := errors.Cause(err) // want `pkg/errors.Cause can be replaced with an errors.Unwrap loop`
+		return cause
+	}
+	return nil
+}
+
+func Is(err error) bool {
+	var target *Error
+	return errors.Is(err, ErrNotFound) || errors.As(err, &target) // want `pkg/errors.Is can be replaced with errors.Is` `pkg/errors.As can be replaced with errors.As`
+}
+
+type Error struct{}
+
+func (*Error) Error() string { return "error" }
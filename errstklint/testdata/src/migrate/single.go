@@ -0,0 +1,7 @@
+package migrate
+
+import "github.com/pkg/errors"
+
+func Invalid(field string) error {
+	return errors.Errorf("invalid %s", field) // want `pkg/errors.Errorf can be replaced with fmt.Errorf and errstk.With`
+}
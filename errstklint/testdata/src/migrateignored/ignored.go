@@ -0,0 +1,8 @@
+//lint:file-ignore errstkmigrate migrated later
+package migrateignored
+
+import "github.com/pkg/errors"
+
+func Ignored() error {
+	return errors.New("ignored")
+}
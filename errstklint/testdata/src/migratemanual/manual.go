@@ -0,0 +1,57 @@
+package migratemanual
+
+import (
+	"github.com/pkg/errors"
+)
+
+var wrap = errors.Wrap // want `pkg/errors.Wrap needs manual migration: it is not called`
+
+func Unchecked(err error) error {
+	return errors.Wrap(err, "unchecked") // want `pkg/errors.Wrap needs manual migration: the error may be nil`
+}
+
+func OtherVariable(err, other error) error {
+	if other != nil {
+		return errors.Wrapf(err, "other %v", other) // want `pkg/errors.Wrapf needs manual migration: the error may be nil`
+	}
+	return nil
+}
+
+func InClosure(err error) func() error {
+	if err != nil {
+		return func() error {
+			return errors.WithMessage(err, "closure") // want `pkg/errors.WithMessage needs manual migration: the error may be nil`
+		}
+	}
+	return nil
+}
+
+func Format(err error, format string) error {
+	if err != nil {
+		return errors.Wrapf(err, format) // want `pkg/errors.Wrapf needs manual migration: the format is not a string literal`
+	}
+	return nil
+}
+
+func Spread(err error, args []interface{}) error {
+	if err != nil {
+		return errors.Wrapf(err, "%v", args...) // want `pkg/errors.Wrapf needs manual migration: the arguments are passed with \.\.\.`
+	}
+	return nil
+}
+
+func TypeSwitch(err error) string {
+	switch errors.Cause(err).(type) { // want `pkg/errors.Cause needs manual migration: only comparisons and assignments`
+	case nil:
+		return "nil"
+	}
+	return "other"
+}
+
+func Trace(err error) errors.StackTrace { // want `pkg/errors.StackTrace needs manual migration: it has no equivalent`
+	return nil
+}
+
+func Convertible() error {
+	return errors.New("convertible") // want `pkg/errors.New can be replaced with errors.New and errstk.With \(auto-fix unavailable: other uses of pkg/errors in this file need manual migration\)`
+}
@@ -0,0 +1,12 @@
+package migratemanual
+
+import "github.com/pkg/errors"
+
+//nolint:errstkmigrate
+func Ignored(err error) error {
+	return errors.Wrap(err, "ignored")
+}
+
+func Reported() error {
+	return errors.New("reported") // want `pkg/errors.New can be replaced with errors.New and errstk.With \(auto-fix unavailable: other uses of pkg/errors in this file need manual migration\)`
+}
@@ -0,0 +1,107 @@
+// Package errstkotel records errstk stack traces on OpenTelemetry spans.
+//
+// span.RecordError only records the stack of the goroutine calling it, if any.
+// RecordError instead records one exception event per stack captured by errstk,
+// with exception.stacktrace taken from the captured frames.
+//
+// This package is a separate module, so that using errstk does not add the
+// OpenTelemetry dependencies to your module.
+package errstkotel
+
+import (
+	"errors"
+	"fmt"
+	"go/token"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/tomoemon/go-errstk"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.43.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// errstkPkgPath is the import path of errstk, whose unexported wrapper types
+// are skipped when reporting exception.type.
+var errstkPkgPath = reflect.TypeFor[errstk.StackFrame]().PkgPath()
+
+// exceptionLabelKey is the event attribute holding the errstk.StackLabel of a stack.
+const exceptionLabelKey = attribute.Key("exception.label")
+
+// RecordError records err on span as "exception" events, one for each stack
+// found by errstk.WalkStack, in the same order.
+// Each event has the attributes:
+//   - exception.type: the Go type of the error that carries the stack,
+//     looking through errstk's own wrapper types
+//   - exception.message: the message of that error
+//   - exception.stacktrace: its frames, formatted with errstk.DefaultStackFrameFormatter
+//   - exception.label: the errstk.StackLabel of a stack attached by Rethrow,
+//     Group or Go; omitted for the stack where the error started
+//
+// If err has no stack trace, a single event without exception.stacktrace is
+// recorded for err itself. opts are applied to every event; trace.WithStackTrace
+// is ignored, because the stack of the caller is not the one that matters.
+// Does nothing if err is nil or span is not recording.
+//
+// Example:
+//
+//	if err != nil {
+//	    errstkotel.RecordError(span, err)
+//	    span.SetStatus(codes.Error, err.Error())
+//	}
+func RecordError(span trace.Span, err error, opts ...trace.EventOption) {
+	if err == nil || !span.IsRecording() {
+		return
+	}
+	recorded := false
+	errstk.WalkStack(err, func(err error, frames []errstk.StackFrame) {
+		recorded = true
+		attrs := []attribute.KeyValue{semconv.ExceptionStacktrace(formatFrames(frames))}
+		if label := errstk.StackLabel(err); label != "" {
+			attrs = append(attrs, exceptionLabelKey.String(label))
+		}
+		addException(span, err, opts, attrs...)
+	})
+	if !recorded {
+		addException(span, err, opts)
+	}
+}
+
+// addException adds an "exception" event for err with the given extra attributes.
+func addException(span trace.Span, err error, opts []trace.EventOption, attrs ...attribute.KeyValue) {
+	attrs = append([]attribute.KeyValue{
+		semconv.ExceptionType(typeName(err)),
+		semconv.ExceptionMessage(err.Error()),
+	}, attrs...)
+	span.AddEvent(semconv.ExceptionEventName, append(slices.Clip(opts), trace.WithAttributes(attrs...))...)
+}
+
+// typeName returns the Go type of err, looking through errstk's unexported
+// wrappers to the error they wrap.
+func typeName(err error) string {
+	for {
+		t := reflect.TypeOf(err)
+		if t.Kind() == reflect.Pointer {
+			t = t.Elem()
+		}
+		if t.PkgPath() != errstkPkgPath || t.Name() == "" || token.IsExported(t.Name()) {
+			break
+		}
+		inner := errors.Unwrap(err)
+		if inner == nil {
+			break
+		}
+		err = inner
+	}
+	return fmt.Sprintf("%T", err)
+}
+
+// formatFrames formats frames in the same way as errstk.ErrorStack.
+func formatFrames(frames []errstk.StackFrame) string {
+	var b strings.Builder
+	for i := range frames {
+		b.WriteString(frames[i].String())
+	}
+	return b.String()
+}
@@ -0,0 +1,126 @@
+package errstkotel
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/tomoemon/go-errstk"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// recordEvents records err on a new span and returns the events of the exported span.
+func recordEvents(t *testing.T, err error, opts ...trace.EventOption) []sdktrace.Event {
+	t.Helper()
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	t.Cleanup(func() { _ = provider.Shutdown(context.Background()) })
+
+	_, span := provider.Tracer("test").Start(context.Background(), "operation")
+	RecordError(span, err, opts...)
+	span.End()
+
+	spans := exporter.GetSpans()
+	if len(spans) != 1 {
+		t.Fatalf("exported %d spans, want 1", len(spans))
+	}
+	return spans[0].Events
+}
+
+func attributes(event sdktrace.Event) map[attribute.Key]string {
+	m := make(map[attribute.Key]string)
+	for _, kv := range event.Attributes {
+		m[kv.Key] = kv.Value.Emit()
+	}
+	return m
+}
+
+//go:noinline
+func loadUser() error {
+	return errstk.With(errors.New("not found"), "user_id", 42)
+}
+
+func TestRecordError(t *testing.T) {
+	t.Run("nil error records nothing", func(t *testing.T) {
+		if events := recordEvents(t, nil); len(events) != 0 {
+			t.Errorf("recorded %d events, want 0", len(events))
+		}
+	})
+
+	t.Run("stack is taken from the captured frames", func(t *testing.T) {
+		events := recordEvents(t, fmt.Errorf("handler: %w", loadUser()))
+		if len(events) != 1 {
+			t.Fatalf("recorded %d events, want 1", len(events))
+		}
+		if events[0].Name != "exception" {
+			t.Errorf("event name = %q, want exception", events[0].Name)
+		}
+		attrs := attributes(events[0])
+		if got := attrs["exception.type"]; got != "*errors.errorString" {
+			t.Errorf("exception.type = %q, want *errors.errorString", got)
+		}
+		if got := attrs["exception.message"]; got != "not found" {
+			t.Errorf("exception.message = %q, want not found", got)
+		}
+		stacktrace := attrs["exception.stacktrace"]
+		if !strings.HasPrefix(stacktrace, "github.com/tomoemon/go-errstk/errstkotel.loadUser()\n") {
+			t.Errorf("exception.stacktrace should start at loadUser, got:\n%s", stacktrace)
+		}
+		if strings.Contains(stacktrace, "errstkotel.RecordError") {
+			t.Errorf("exception.stacktrace should not contain the recording site, got:\n%s", stacktrace)
+		}
+	})
+
+	t.Run("one event per stack", func(t *testing.T) {
+		err := errors.Join(loadUser(), errstk.With(errors.New("save failed")))
+		events := recordEvents(t, errstk.Rethrow(err))
+		if len(events) != 3 {
+			t.Fatalf("recorded %d events, want 3", len(events))
+		}
+		want := []string{"not found", "save failed", "not found\nsave failed"}
+		for i, event := range events {
+			attrs := attributes(event)
+			if attrs["exception.message"] != want[i] {
+				t.Errorf("event %d exception.message = %q, want %q", i, attrs["exception.message"], want[i])
+			}
+			if attrs["exception.stacktrace"] == "" {
+				t.Errorf("event %d should have exception.stacktrace", i)
+			}
+		}
+		if got := attributes(events[2])["exception.type"]; got != "*errors.joinError" {
+			t.Errorf("rethrown exception.type = %q, want *errors.joinError", got)
+		}
+	})
+
+	t.Run("rethrown stacks carry their label", func(t *testing.T) {
+		events := recordEvents(t, errstk.Rethrow(fmt.Errorf("ctx: %w", loadUser())))
+		if len(events) != 2 {
+			t.Fatalf("recorded %d events, want 2", len(events))
+		}
+		if label, ok := attributes(events[0])["exception.label"]; ok {
+			t.Errorf("origin stack should have no exception.label, got %q", label)
+		}
+		if got := attributes(events[1])["exception.label"]; got != "rethrown at" {
+			t.Errorf("exception.label = %q, want %q", got, "rethrown at")
+		}
+	})
+
+	t.Run("error without stack", func(t *testing.T) {
+		events := recordEvents(t, errors.New("plain"), trace.WithAttributes(attribute.String("extra", "value")))
+		if len(events) != 1 {
+			t.Fatalf("recorded %d events, want 1", len(events))
+		}
+		attrs := attributes(events[0])
+		if _, ok := attrs["exception.stacktrace"]; ok {
+			t.Error("error without stack should not have exception.stacktrace")
+		}
+		if attrs["exception.message"] != "plain" || attrs["extra"] != "value" {
+			t.Errorf("attributes = %v, want message and extra attribute", attrs)
+		}
+	})
+}
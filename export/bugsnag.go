@@ -0,0 +1,160 @@
+package export
+
+import (
+	"runtime/debug"
+	"slices"
+	"strconv"
+	"sync"
+
+	"github.com/tomoemon/go-errstk"
+)
+
+// BugsnagPayload is the body of a request to the Bugsnag error reporting API.
+// See https://bugsnagerrorreportingapi.docs.apiary.io/ for the schema.
+type BugsnagPayload struct {
+	APIKey         string          `json:"apiKey"`
+	PayloadVersion string          `json:"payloadVersion"`
+	Notifier       BugsnagNotifier `json:"notifier"`
+	Events         []BugsnagEvent  `json:"events"`
+}
+
+// BugsnagNotifier describes the library that sent the payload.
+type BugsnagNotifier struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+	URL     string `json:"url"`
+}
+
+// BugsnagEvent is one error event.
+type BugsnagEvent struct {
+	// Exceptions lists the errors of the chain, the outermost error first.
+	Exceptions     []BugsnagException    `json:"exceptions"`
+	Severity       string                `json:"severity"`
+	SeverityReason BugsnagSeverityReason `json:"severityReason"`
+	Unhandled      bool                  `json:"unhandled"`
+	// MetaData holds the fields attached to the error chain in the "fields" tab.
+	MetaData map[string]map[string]any `json:"metaData,omitempty"`
+}
+
+// BugsnagSeverityReason explains why the event has its severity.
+type BugsnagSeverityReason struct {
+	Type string `json:"type"`
+}
+
+// BugsnagException is one error of the chain.
+type BugsnagException struct {
+	ErrorClass string         `json:"errorClass"`
+	Message    string         `json:"message"`
+	Type       string         `json:"type"`
+	Stacktrace []BugsnagFrame `json:"stacktrace"`
+}
+
+// BugsnagFrame is one frame of a stack trace.
+type BugsnagFrame struct {
+	File       string `json:"file"`
+	LineNumber int    `json:"lineNumber"`
+	Method     string `json:"method"`
+	InProject  bool   `json:"inProject,omitempty"`
+	// Code maps line numbers to the source lines around the frame.
+	Code map[string]string `json:"code,omitempty"`
+}
+
+// errstkModule is the module path of errstk.
+const errstkModule = "github.com/tomoemon/go-errstk"
+
+// bugsnagNotifier identifies errstk as the notifier.
+var bugsnagNotifier = sync.OnceValue(func() BugsnagNotifier {
+	info, _ := debug.ReadBuildInfo()
+	return BugsnagNotifier{
+		Name:    "go-errstk",
+		Version: moduleVersion(info, errstkModule),
+		URL:     "https://" + errstkModule,
+	}
+})
+
+// moduleVersion returns the version of the module path recorded in info.
+// Returns "devel" if info is nil, the module is not found or it has no
+// released version, e.g. when it is the main module.
+func moduleVersion(info *debug.BuildInfo, path string) string {
+	if info == nil {
+		return "devel"
+	}
+	mods := append([]*debug.Module{&info.Main}, info.Deps...)
+	for _, mod := range mods {
+		if mod.Path != path {
+			continue
+		}
+		if mod.Replace != nil {
+			mod = mod.Replace
+		}
+		if mod.Version == "" || mod.Version == "(devel)" {
+			return "devel"
+		}
+		return mod.Version
+	}
+	return "devel"
+}
+
+// NewBugsnagPayload converts the error chain of err into a Bugsnag payload with
+// a single handled event. Each error in the chain becomes an exception, in
+// depth-first order starting with err itself; frames are ordered most recent call first.
+// Returns nil if err is nil.
+func NewBugsnagPayload(err error, apiKey string, opts Options) *BugsnagPayload {
+	if err == nil {
+		return nil
+	}
+	event := BugsnagEvent{
+		Severity:       "warning",
+		SeverityReason: BugsnagSeverityReason{Type: "handledException"},
+	}
+	if fields := errstk.JSONFields(err); fields != nil {
+		event.MetaData = map[string]map[string]any{"fields": fields}
+	}
+	var add func(node *errstk.JSONError)
+	add = func(node *errstk.JSONError) {
+		event.Exceptions = append(event.Exceptions, BugsnagException{
+			ErrorClass: node.Type,
+			Message:    node.Message,
+			Type:       "go",
+			Stacktrace: bugsnagStacktrace(node.Frames, opts),
+		})
+		for _, child := range node.Children {
+			add(child)
+		}
+	}
+	add(errstk.NewJSONError(err))
+
+	return &BugsnagPayload{
+		APIKey:         apiKey,
+		PayloadVersion: "5",
+		Notifier:       bugsnagNotifier(),
+		Events:         []BugsnagEvent{event},
+	}
+}
+
+func bugsnagStacktrace(frames []errstk.JSONFrame, opts Options) []BugsnagFrame {
+	stacktrace := []BugsnagFrame{}
+	for _, f := range frames {
+		if f.Collapsed > 0 {
+			continue
+		}
+		frame := BugsnagFrame{
+			File:       f.File,
+			LineNumber: f.Line,
+			Method:     f.Function,
+			InProject:  opts.inApp(f),
+		}
+		if f.Package != "" {
+			frame.Method = f.Package + "." + f.Function
+		}
+		if before, line, after, ok := sourceContext(f, opts.ContextLines); ok {
+			frame.Code = make(map[string]string)
+			first := f.Line - len(before)
+			for i, line := range slices.Concat(before, []string{line}, after) {
+				frame.Code[strconv.Itoa(first+i)] = line
+			}
+		}
+		stacktrace = append(stacktrace, frame)
+	}
+	return stacktrace
+}
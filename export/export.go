@@ -0,0 +1,67 @@
+// Package export converts errstk error chains into the event formats of error
+// reporting services, without depending on their SDKs or making network calls.
+//
+// The conversion is based on errstk.NewJSONError, so it works for any error
+// chain, including fmt.Errorf wrappers, errors.Join branches and errors decoded
+// with errstk.UnmarshalJSON.
+//
+// Example:
+//
+//	event := export.NewSentryEvent(err, export.Options{ModulePath: "example.com/app"})
+//	envelope, _ := event.Envelope()
+//	// POST envelope to the Sentry envelope endpoint with your own HTTP client.
+package export
+
+import (
+	"strings"
+
+	"github.com/tomoemon/go-errstk"
+)
+
+// Options configures the conversion of an error into an event.
+type Options struct {
+	// ModulePath marks frames of packages under this module path as in-app,
+	// e.g. "example.com/app". Frames of package main are always in-app.
+	ModulePath string
+
+	// ContextLines is the number of source lines to include before and after
+	// the line of each frame, read with errstk.StackFrame.SourceContext.
+	// Frames whose file cannot be read have no context. 0 disables context lines.
+	ContextLines int
+}
+
+// inApp reports whether frame belongs to the application rather than a dependency.
+func (o Options) inApp(frame errstk.JSONFrame) bool {
+	if frame.Package == "main" {
+		return true
+	}
+	if o.ModulePath == "" {
+		return false
+	}
+	mod := strings.TrimSuffix(o.ModulePath, "/")
+	return frame.Package == mod || strings.HasPrefix(frame.Package, mod+"/")
+}
+
+// sourceContext returns the lines around the line of frame,
+// or false if contextLines is 0 or the source is not available.
+func sourceContext(frame errstk.JSONFrame, contextLines int) (before []string, line string, after []string, ok bool) {
+	if contextLines <= 0 {
+		return nil, "", nil, false
+	}
+	sf := errstk.StackFrame{File: frame.File, LineNumber: frame.Line, Package: frame.Package}
+	lines, err := sf.SourceContext(contextLines, contextLines)
+	if err != nil || len(lines) == 0 {
+		return nil, "", nil, false
+	}
+	for _, l := range lines {
+		switch {
+		case l.Current:
+			line = l.Text
+		case l.Number < frame.Line:
+			before = append(before, l.Text)
+		default:
+			after = append(after, l.Text)
+		}
+	}
+	return before, line, after, true
+}
@@ -0,0 +1,173 @@
+package export
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"testing"
+	"time"
+
+	"github.com/tomoemon/go-errstk"
+)
+
+var update = flag.Bool("update", false, "update golden files")
+
+// loadError decodes testdata/error.json, so that frames do not depend on the build.
+func loadError(t *testing.T) error {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", "error.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := errstk.UnmarshalJSON(data)
+	if err != nil {
+		t.Fatalf("UnmarshalJSON returned error: %v", err)
+	}
+	return decoded
+}
+
+// checkGolden compares got with the golden file, or rewrites it with -update.
+func checkGolden(t *testing.T, name string, got []byte) {
+	t.Helper()
+	path := filepath.Join("testdata", name)
+	if *update {
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("%s does not match the golden file; run go test -update to update it\ngot:\n%s", name, got)
+	}
+}
+
+func marshalIndent(t *testing.T, v any) []byte {
+	t.Helper()
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		t.Fatalf("MarshalIndent returned error: %v", err)
+	}
+	return append(data, '\n')
+}
+
+var testOptions = Options{ModulePath: "example.com/app", ContextLines: 2}
+
+func newTestSentryEvent(t *testing.T) *SentryEvent {
+	t.Helper()
+	event := NewSentryEvent(loadError(t), testOptions)
+	event.EventID = "0123456789abcdef0123456789abcdef"
+	event.Timestamp = time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	return event
+}
+
+func TestSentry(t *testing.T) {
+	t.Run("nil error", func(t *testing.T) {
+		if event := NewSentryEvent(nil, testOptions); event != nil {
+			t.Errorf("NewSentryEvent(nil) = %v, want nil", event)
+		}
+	})
+
+	t.Run("event", func(t *testing.T) {
+		checkGolden(t, "sentry_event.golden.json", marshalIndent(t, newTestSentryEvent(t)))
+	})
+
+	t.Run("envelope", func(t *testing.T) {
+		envelope, err := newTestSentryEvent(t).Envelope()
+		if err != nil {
+			t.Fatalf("Envelope returned error: %v", err)
+		}
+		checkGolden(t, "sentry_envelope.golden", envelope)
+	})
+
+	t.Run("random event ID", func(t *testing.T) {
+		a := NewSentryEvent(errors.New("test error"), Options{})
+		b := NewSentryEvent(errors.New("test error"), Options{})
+		if len(a.EventID) != 32 || a.EventID == b.EventID {
+			t.Errorf("event IDs should be random 32 hex digits, got %q and %q", a.EventID, b.EventID)
+		}
+	})
+}
+
+func TestBugsnag(t *testing.T) {
+	t.Run("nil error", func(t *testing.T) {
+		if payload := NewBugsnagPayload(nil, "key", testOptions); payload != nil {
+			t.Errorf("NewBugsnagPayload(nil) = %v, want nil", payload)
+		}
+	})
+
+	t.Run("payload", func(t *testing.T) {
+		payload := NewBugsnagPayload(loadError(t), "0123456789abcdef0123456789abcdef", testOptions)
+		checkGolden(t, "bugsnag_payload.golden.json", marshalIndent(t, payload))
+	})
+}
+
+func TestUnencodableFields(t *testing.T) {
+	ch := make(chan int)
+	err := errstk.With(errors.New("test error"), "ch", ch)
+
+	if _, mErr := json.Marshal(NewSentryEvent(err, Options{})); mErr != nil {
+		t.Errorf("Sentry event should marshal, got error: %v", mErr)
+	}
+	if _, mErr := json.Marshal(NewBugsnagPayload(err, "key", Options{})); mErr != nil {
+		t.Errorf("Bugsnag payload should marshal, got error: %v", mErr)
+	}
+}
+
+func TestInApp(t *testing.T) {
+	tests := []struct {
+		modulePath string
+		pkg        string
+		want       bool
+	}{
+		{"example.com/app", "example.com/app", true},
+		{"example.com/app", "example.com/app/internal/db", true},
+		{"example.com/app/", "example.com/app/internal/db", true},
+		{"example.com/app", "example.com/application", false},
+		{"example.com/app", "net/http", false},
+		{"example.com/app", "main", true},
+		{"", "example.com/app", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.modulePath+" "+tt.pkg, func(t *testing.T) {
+			opts := Options{ModulePath: tt.modulePath}
+			if got := opts.inApp(errstk.JSONFrame{Package: tt.pkg}); got != tt.want {
+				t.Errorf("inApp() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestModuleVersion(t *testing.T) {
+	const path = "github.com/tomoemon/go-errstk"
+	tests := []struct {
+		name string
+		info *debug.BuildInfo
+		want string
+	}{
+		{"no build info", nil, "devel"},
+		{"main module", &debug.BuildInfo{Main: debug.Module{Path: path, Version: "(devel)"}}, "devel"},
+		{"dependency", &debug.BuildInfo{
+			Main: debug.Module{Path: "example.com/app", Version: "(devel)"},
+			Deps: []*debug.Module{{Path: path, Version: "v1.2.3"}},
+		}, "v1.2.3"},
+		{"replaced dependency", &debug.BuildInfo{
+			Deps: []*debug.Module{{Path: path, Version: "v1.2.3", Replace: &debug.Module{Path: "../go-errstk"}}},
+		}, "devel"},
+		{"not a dependency", &debug.BuildInfo{Main: debug.Module{Path: "example.com/app"}}, "devel"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := moduleVersion(tt.info, path); got != tt.want {
+				t.Errorf("moduleVersion() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,185 @@
+package export
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/tomoemon/go-errstk"
+)
+
+// SentryEvent is a Sentry event carrying an error chain.
+// See https://develop.sentry.dev/sdk/data-model/event-payloads/ for the schema.
+type SentryEvent struct {
+	EventID   string          `json:"event_id"`
+	Timestamp time.Time       `json:"timestamp"`
+	Platform  string          `json:"platform"`
+	Level     string          `json:"level"`
+	Exception SentryException `json:"exception"`
+	// Extra holds the fields attached to the error chain.
+	Extra map[string]any `json:"extra,omitempty"`
+}
+
+// SentryException is the exception interface of a Sentry event.
+type SentryException struct {
+	// Values lists the errors of the chain, the outermost error last.
+	Values []SentryExceptionValue `json:"values"`
+}
+
+// SentryExceptionValue is one error of the chain.
+type SentryExceptionValue struct {
+	Type       string            `json:"type"`
+	Value      string            `json:"value"`
+	Stacktrace *SentryStacktrace `json:"stacktrace,omitempty"`
+	Mechanism  *SentryMechanism  `json:"mechanism,omitempty"`
+}
+
+// SentryStacktrace is the stack trace of an error.
+type SentryStacktrace struct {
+	// Frames are ordered oldest call first, as Sentry expects.
+	Frames []SentryFrame `json:"frames"`
+}
+
+// SentryFrame is one frame of a stack trace.
+type SentryFrame struct {
+	Function    string   `json:"function"`
+	Module      string   `json:"module,omitempty"`
+	Filename    string   `json:"filename,omitempty"`
+	AbsPath     string   `json:"abs_path,omitempty"`
+	Lineno      int      `json:"lineno,omitempty"`
+	InApp       bool     `json:"in_app"`
+	PreContext  []string `json:"pre_context,omitempty"`
+	ContextLine string   `json:"context_line,omitempty"`
+	PostContext []string `json:"post_context,omitempty"`
+}
+
+// SentryMechanism links the errors of a chain into a tree, so that Sentry can
+// show fmt.Errorf wrappers and errors.Join branches as an exception group.
+type SentryMechanism struct {
+	Type             string `json:"type"`
+	Source           string `json:"source,omitempty"`
+	ExceptionID      int    `json:"exception_id"`
+	ParentID         *int   `json:"parent_id,omitempty"`
+	IsExceptionGroup bool   `json:"is_exception_group,omitempty"`
+}
+
+// NewSentryEvent converts the error chain of err into a Sentry event with a random
+// event ID and the current time. Each error in the chain becomes an exception value,
+// linked to the error that wraps it through its mechanism.
+// Returns nil if err is nil.
+func NewSentryEvent(err error, opts Options) *SentryEvent {
+	if err == nil {
+		return nil
+	}
+	var id [16]byte
+	_, _ = rand.Read(id[:])
+
+	event := &SentryEvent{
+		EventID:   hex.EncodeToString(id[:]),
+		Timestamp: time.Now().UTC(),
+		Platform:  "go",
+		Level:     "error",
+		Extra:     errstk.JSONFields(err),
+	}
+	c := sentryConverter{opts: opts}
+	c.add(errstk.NewJSONError(err), nil, "")
+	// Sentry expects the outermost error last.
+	slices.Reverse(c.values)
+	event.Exception.Values = c.values
+	return event
+}
+
+// Envelope encodes the event as a Sentry envelope with a single event item.
+// See https://develop.sentry.dev/sdk/data-model/envelopes/ for the format.
+func (e *SentryEvent) Envelope() ([]byte, error) {
+	payload, err := json.Marshal(e)
+	if err != nil {
+		return nil, err
+	}
+	header, err := json.Marshal(struct {
+		EventID string    `json:"event_id"`
+		SentAt  time.Time `json:"sent_at"`
+	}{e.EventID, e.Timestamp})
+	if err != nil {
+		return nil, err
+	}
+	itemHeader, err := json.Marshal(struct {
+		Type   string `json:"type"`
+		Length int    `json:"length"`
+	}{"event", len(payload)})
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	for _, line := range [][]byte{header, itemHeader, payload} {
+		buf.Write(line)
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes(), nil
+}
+
+// sentryConverter flattens an error tree into exception values in pre-order.
+type sentryConverter struct {
+	opts   Options
+	values []SentryExceptionValue
+}
+
+func (c *sentryConverter) add(node *errstk.JSONError, parentID *int, source string) {
+	id := len(c.values)
+	mechanism := &SentryMechanism{
+		Type:             "generic",
+		ExceptionID:      id,
+		ParentID:         parentID,
+		Source:           source,
+		IsExceptionGroup: len(node.Children) > 1,
+	}
+	if parentID != nil {
+		mechanism.Type = "chained"
+	}
+	c.values = append(c.values, SentryExceptionValue{
+		Type:       node.Type,
+		Value:      node.Message,
+		Stacktrace: c.stacktrace(node.Frames),
+		Mechanism:  mechanism,
+	})
+
+	for i, child := range node.Children {
+		source := "cause"
+		if len(node.Children) > 1 {
+			source = "errors[" + strconv.Itoa(i) + "]"
+		}
+		c.add(child, &id, source)
+	}
+}
+
+func (c *sentryConverter) stacktrace(frames []errstk.JSONFrame) *SentryStacktrace {
+	var st SentryStacktrace
+	for _, f := range frames {
+		if f.Collapsed > 0 {
+			continue
+		}
+		frame := SentryFrame{
+			Function: f.Function,
+			Module:   f.Package,
+			Filename: f.File,
+			AbsPath:  f.File,
+			Lineno:   f.Line,
+			InApp:    c.opts.inApp(f),
+		}
+		if before, line, after, ok := sourceContext(f, c.opts.ContextLines); ok {
+			frame.PreContext, frame.ContextLine, frame.PostContext = before, line, after
+		}
+		st.Frames = append(st.Frames, frame)
+	}
+	if st.Frames == nil {
+		return nil
+	}
+	// Sentry expects the oldest call first.
+	slices.Reverse(st.Frames)
+	return &st
+}
@@ -0,0 +1,34 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/tomoemon/go-errstk"
+)
+
+var errNotFound = errors.New("not found")
+
+func loadUser(id int) error {
+	return errstk.With(errNotFound, "user_id", id)
+}
+
+func saveUser() error {
+	return errstk.With(errors.New("save failed"))
+}
+
+func handle(w http.ResponseWriter, r *http.Request) {
+	err := errors.Join(
+		fmt.Errorf("load user: %w", loadUser(42)),
+		saveUser(),
+	)
+	if err != nil {
+		report(fmt.Errorf("handler: %w", err))
+	}
+}
+
+// report stands in for sending err to an error reporting service.
+func report(err error) {
+	fmt.Println(errstk.ErrorStack(err))
+}
@@ -0,0 +1,83 @@
+package errstk
+
+import "reflect"
+
+// StackExtractor returns the stack carried by err itself, not by the errors it wraps,
+// or false if err carries none.
+type StackExtractor func(err error) (Stack, bool)
+
+// DefaultStackExtractors are the functions used to find the stacks of errors of other
+// libraries. They are tried in order on each error of a chain, and the first stack
+// found is used by WalkStack, ErrorStack, StackOf, HasStack and the check that keeps
+// With and Wrap from adding a second stack.
+//
+// The built-in extractors match methods by their shape, without depending on the
+// libraries that define them:
+//   - Callers() []uintptr: StackTracer, including errors of go-errors and bugsnag-go
+//   - StackTrace() T, where T is a slice of a uintptr type: pkg/errors and
+//     libraries compatible with it, such as cockroachdb/errors
+//
+// Example - Recognizing another error type:
+//
+//	func init() {
+//	    errstk.DefaultStackExtractors = append(errstk.DefaultStackExtractors,
+//	        func(err error) (errstk.Stack, bool) {
+//	            if e, ok := err.(*mylib.Error); ok {
+//	                return errstk.Stack(e.PCs), true
+//	            }
+//	            return nil, false
+//	        })
+//	}
+//
+// Note: This setting is global and should be set at package initialization time only
+// to avoid race conditions.
+var DefaultStackExtractors = []StackExtractor{extractCallers, extractStackTrace}
+
+// extractCallers returns the stack of a StackTracer.
+func extractCallers(err error) (Stack, bool) {
+	if tracer, ok := err.(StackTracer); ok {
+		return Stack(tracer.Callers()), true
+	}
+	return nil, false
+}
+
+// extractStackTrace returns the stack of an error with a StackTrace method returning
+// a slice of program counters, such as github.com/pkg/errors.StackTrace. The frames
+// of pkg/errors hold the values returned by runtime.Callers, like Stack.
+func extractStackTrace(err error) (Stack, bool) {
+	method := reflect.ValueOf(err).MethodByName("StackTrace")
+	if !method.IsValid() {
+		return nil, false
+	}
+	typ := method.Type()
+	if typ.NumIn() != 0 || typ.NumOut() != 1 || typ.Out(0).Kind() != reflect.Slice || typ.Out(0).Elem().Kind() != reflect.Uintptr {
+		return nil, false
+	}
+	trace := method.Call(nil)[0]
+	stack := make(Stack, trace.Len())
+	for i := range stack {
+		stack[i] = uintptr(trace.Index(i).Uint())
+	}
+	return stack, true
+}
+
+// extractStack returns the stack carried by err itself using DefaultStackExtractors.
+func extractStack(err error) (Stack, bool) {
+	for _, extract := range DefaultStackExtractors {
+		if stack, ok := extract(err); ok {
+			return stack, true
+		}
+	}
+	return nil, false
+}
+
+// findStack returns the stack of the first error in the chain of err that carries one,
+// in the order of walkChain.
+func findStack(err error) (Stack, bool) {
+	var found chainError
+	walkChain(err, func(e chainError) bool {
+		found = e
+		return !e.hasStack
+	})
+	return found.stack, found.hasStack
+}
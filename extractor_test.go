@@ -0,0 +1,123 @@
+package errstk
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// setStackExtractors replaces DefaultStackExtractors for the duration of a test.
+func setStackExtractors(t *testing.T, extractors []StackExtractor) {
+	t.Helper()
+	old := DefaultStackExtractors
+	DefaultStackExtractors = extractors
+	t.Cleanup(func() {
+		DefaultStackExtractors = old
+	})
+}
+
+// pkgFrame and pkgStackTrace have the shape of github.com/pkg/errors.Frame and StackTrace.
+type pkgFrame uintptr
+
+type pkgStackTrace []pkgFrame
+
+// pkgError has the shape of the errors created by github.com/pkg/errors.
+type pkgError struct {
+	msg   string
+	stack []uintptr
+}
+
+func (e *pkgError) Error() string { return e.msg }
+
+func (e *pkgError) StackTrace() pkgStackTrace {
+	trace := make(pkgStackTrace, len(e.stack))
+	for i, pc := range e.stack {
+		trace[i] = pkgFrame(pc)
+	}
+	return trace
+}
+
+//go:noinline
+func newPkgError(msg string) *pkgError {
+	return &pkgError{msg: msg, stack: callers(3, DefaultMaxStackDepth)}
+}
+
+// textTraceError has a StackTrace method of a different shape.
+type textTraceError struct{}
+
+func (textTraceError) Error() string      { return "text trace" }
+func (textTraceError) StackTrace() string { return "main.main()" }
+
+// untracedError carries a stack that no built-in extractor recognizes.
+type untracedError struct {
+	pcs []uintptr
+}
+
+func (e *untracedError) Error() string { return "custom" }
+
+func TestStackExtractors(t *testing.T) {
+	t.Run("pkg/errors StackTrace", func(t *testing.T) {
+		pkgErr := newPkgError("pkg error")
+		err := With(fmt.Errorf("context: %w", pkgErr))
+		if _, ok := err.(*withStack); ok {
+			t.Error("With should not add a second stack to a pkg/errors error")
+		}
+
+		var visited int
+		WalkStack(err, func(e error, frames []StackFrame) {
+			visited++
+			if e != pkgErr {
+				t.Errorf("WalkStack visited %v, want the pkg/errors error", e)
+			}
+			if len(frames) == 0 || frames[0].Name != "TestStackExtractors.func1" {
+				t.Errorf("first frame = %v, want TestStackExtractors.func1", frames)
+			}
+		})
+		if visited != 1 {
+			t.Errorf("WalkStack visited %d stacks, want 1", visited)
+		}
+		if got := ErrorStack(err); !strings.Contains(got, "go-errstk.TestStackExtractors.func1()") {
+			t.Errorf("ErrorStack() = %q, want the pkg/errors stack", got)
+		}
+	})
+
+	t.Run("StackTrace of another shape", func(t *testing.T) {
+		if HasStack(textTraceError{}) {
+			t.Error("HasStack should ignore StackTrace methods not returning program counters")
+		}
+		if _, ok := With(textTraceError{}).(*withStack); !ok {
+			t.Error("With should add a stack")
+		}
+	})
+
+	t.Run("errors.Join branches", func(t *testing.T) {
+		err := errors.Join(errors.New("plain"), newPkgError("pkg error"))
+		if stack, ok := StackOf(err); !ok || len(stack) == 0 {
+			t.Error("StackOf should find the stack in a joined branch")
+		}
+	})
+
+	t.Run("custom extractor", func(t *testing.T) {
+		custom := &untracedError{pcs: callers(1, DefaultMaxStackDepth)}
+		if HasStack(custom) {
+			t.Fatal("untracedError should not be recognized by the built-in extractors")
+		}
+
+		setStackExtractors(t, append(DefaultStackExtractors, func(err error) (Stack, bool) {
+			if e, ok := err.(*untracedError); ok {
+				return Stack(e.pcs), true
+			}
+			return nil, false
+		}))
+		if !HasStack(fmt.Errorf("wrapped: %w", custom)) {
+			t.Error("HasStack should use the custom extractor")
+		}
+		if _, ok := With(custom).(*withStack); ok {
+			t.Error("With should not add a second stack")
+		}
+		if got := ErrorStack(custom); !strings.Contains(got, "go-errstk.TestStackExtractors.func4()") {
+			t.Errorf("ErrorStack() = %q, want the custom stack", got)
+		}
+	})
+}
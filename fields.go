@@ -0,0 +1,113 @@
+package errstk
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+)
+
+// badKey is the key used for a value that is not preceded by a string key,
+// matching the behavior of log/slog.
+const badKey = "!BADKEY"
+
+// Field is a key/value pair attached to an error by With or Wrap.
+type Field struct {
+	Key   string
+	Value any
+}
+
+// String returns the field formatted as "key=value".
+func (f Field) String() string {
+	return fmt.Sprintf("%s=%v", f.Key, f.Value)
+}
+
+// Fields collects the fields attached to every error in the chain of err,
+// including fmt.Errorf wrappers and errors.Join branches.
+// Fields of outer errors come before fields of the errors they wrap.
+// Returns nil if no fields are attached.
+//
+// Example:
+//
+//	err := errstk.With(errors.New("not found"), "user_id", 42)
+//	for _, f := range errstk.Fields(fmt.Errorf("load: %w", err)) {
+//	    fmt.Println(f.Key, f.Value) // user_id 42
+//	}
+func Fields(err error) []Field {
+	var fields []Field
+	walkChain(err, func(e chainError) bool {
+		if f, ok := e.err.(interface{ errorFields() []Field }); ok {
+			fields = append(fields, f.errorFields()...)
+		}
+		return true
+	})
+	return fields
+}
+
+// withFields attaches fields to an error that already has a stack trace.
+type withFields struct {
+	error
+	fields []Field
+}
+
+func (w *withFields) Format(s fmt.State, verb rune) {
+	formatError(s, verb, w)
+}
+
+// LogValue satisfies the slog.LogValuer interface.
+// See withStack.LogValue for the format.
+func (w *withFields) LogValue() slog.Value {
+	return logValue(w)
+}
+
+// MarshalJSON satisfies the json.Marshaler interface.
+func (w *withFields) MarshalJSON() ([]byte, error) {
+	return MarshalJSON(w)
+}
+
+// Unwrap provides compatibility for Go 1.13 error chains.
+func (w *withFields) Unwrap() error {
+	return w.error
+}
+
+func (w *withFields) errorFields() []Field {
+	return w.fields
+}
+
+func (w *withStack) errorFields() []Field {
+	return w.fields
+}
+
+// newFields converts alternating key/value pairs into fields,
+// in the same way as slog.Logger.Log.
+func newFields(keyvals []any) []Field {
+	var fields []Field
+	for len(keyvals) > 0 {
+		switch k := keyvals[0].(type) {
+		case Field:
+			fields = append(fields, k)
+			keyvals = keyvals[1:]
+		case string:
+			if len(keyvals) == 1 {
+				fields = append(fields, Field{Key: badKey, Value: k})
+				keyvals = nil
+			} else {
+				fields = append(fields, Field{Key: k, Value: keyvals[1]})
+				keyvals = keyvals[2:]
+			}
+		default:
+			fields = append(fields, Field{Key: badKey, Value: k})
+			keyvals = keyvals[1:]
+		}
+	}
+	return fields
+}
+
+// formatFields returns the line used to show fields in ErrorStack,
+// e.g. "fields: user_id=42 request_id=abc".
+func formatFields(fields []Field) string {
+	parts := make([]string, len(fields))
+	for i, f := range fields {
+		parts[i] = f.String()
+	}
+	return "fields: " + strings.Join(parts, " ")
+}
@@ -0,0 +1,157 @@
+package errstk
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestFields(t *testing.T) {
+	t.Run("nil error has no fields", func(t *testing.T) {
+		if fields := Fields(nil); fields != nil {
+			t.Errorf("Fields(nil) = %v, want nil", fields)
+		}
+	})
+
+	t.Run("With attaches key/value pairs", func(t *testing.T) {
+		err := With(errors.New("not found"), "user_id", 42, "request_id", "abc")
+
+		want := []Field{{"user_id", 42}, {"request_id", "abc"}}
+		if got := Fields(err); fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Errorf("Fields() = %v, want %v", got, want)
+		}
+		if _, ok := err.(*withStack); !ok {
+			t.Errorf("With should return *withStack, got %T", err)
+		}
+	})
+
+	t.Run("Wrap attaches key/value pairs", func(t *testing.T) {
+		f := func(id int) (err error) {
+			defer Wrap(&err, "user_id", id)
+			return errors.New("not found")
+		}
+
+		want := []Field{{"user_id", 7}}
+		if got := Fields(f(7)); fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Errorf("Fields() = %v, want %v", got, want)
+		}
+	})
+
+	t.Run("fields are attached to an error that already has a stack", func(t *testing.T) {
+		inner := With(errors.New("not found"), "user_id", 42)
+		outer := With(fmt.Errorf("load: %w", inner), "request_id", "abc")
+
+		if _, ok := outer.(*withFields); !ok {
+			t.Errorf("With should not add a second stack, got %T", outer)
+		}
+		if !errors.Is(outer, inner) {
+			t.Error("should preserve error chain")
+		}
+		want := []Field{{"request_id", "abc"}, {"user_id", 42}}
+		if got := Fields(outer); fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Errorf("Fields() = %v, want %v", got, want)
+		}
+	})
+
+	t.Run("without fields does not wrap an error that already has a stack", func(t *testing.T) {
+		inner := With(errors.New("not found"))
+		if outer := With(inner); outer != inner {
+			t.Error("With without fields should return the error unchanged")
+		}
+	})
+
+	t.Run("collects fields across errors.Join", func(t *testing.T) {
+		err := errors.Join(
+			With(errors.New("error 1"), "a", 1),
+			With(errors.New("error 2"), "b", 2),
+		)
+
+		want := []Field{{"a", 1}, {"b", 2}}
+		if got := Fields(err); fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Errorf("Fields() = %v, want %v", got, want)
+		}
+	})
+
+	t.Run("malformed key/value pairs use !BADKEY", func(t *testing.T) {
+		err := With(errors.New("test error"), 1, "dangling", Field{"explicit", true}, "last")
+
+		want := []Field{{badKey, 1}, {"dangling", Field{"explicit", true}}, {badKey, "last"}}
+		if got := Fields(err); fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Errorf("Fields() = %v, want %v", got, want)
+		}
+	})
+
+	t.Run("Field values are accepted directly", func(t *testing.T) {
+		err := With(errors.New("test error"), Field{"explicit", true}, "user_id", 42)
+
+		want := []Field{{"explicit", true}, {"user_id", 42}}
+		if got := Fields(err); fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Errorf("Fields() = %v, want %v", got, want)
+		}
+	})
+}
+
+func TestFieldsOutput(t *testing.T) {
+	t.Run("%+v shows fields", func(t *testing.T) {
+		err := With(errors.New("not found"), "user_id", 42)
+
+		formatted := fmt.Sprintf("%+v", err)
+		if !strings.HasPrefix(formatted, "not found\nfields: user_id=42\n") {
+			t.Errorf("%%+v should show fields below the message, got:\n%s", formatted)
+		}
+		if !strings.Contains(formatted, "fields_test.go") {
+			t.Errorf("%%+v should still contain the stack, got:\n%s", formatted)
+		}
+	})
+
+	t.Run("%+v on fields-only wrapper shows stack", func(t *testing.T) {
+		err := With(With(errors.New("not found")), "user_id", 42)
+
+		formatted := fmt.Sprintf("%+v", err)
+		if !strings.Contains(formatted, "fields: user_id=42") || !strings.Contains(formatted, "fields_test.go") {
+			t.Errorf("%%+v should show fields and stack, got:\n%s", formatted)
+		}
+	})
+
+	t.Run("ErrorStack shows fields of the whole chain", func(t *testing.T) {
+		err := fmt.Errorf("handler: %w", With(errors.New("not found"), "user_id", 42))
+
+		stackTrace := ErrorStack(err)
+		if !strings.HasPrefix(stackTrace, "handler: not found\nfields: user_id=42\n\nnot found\n") {
+			t.Errorf("ErrorStack should show fields below the full message, got:\n%s", stackTrace)
+		}
+	})
+
+	t.Run("JSON includes fields and round trips", func(t *testing.T) {
+		err := fmt.Errorf("handler: %w", With(errors.New("not found"), "user_id", 42))
+
+		node := NewJSONError(err)
+		if got := node.Children[0].Fields["user_id"]; got != 42 {
+			t.Errorf("fields = %v, want user_id=42", node.Children[0].Fields)
+		}
+
+		data, mErr := MarshalJSON(err)
+		if mErr != nil {
+			t.Fatalf("MarshalJSON returned error: %v", mErr)
+		}
+		decoded, uErr := UnmarshalJSON(data)
+		if uErr != nil {
+			t.Fatalf("UnmarshalJSON returned error: %v", uErr)
+		}
+		want := []Field{{"user_id", float64(42)}}
+		if got := Fields(decoded); fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Errorf("Fields(decoded) = %v, want %v", got, want)
+		}
+	})
+
+	t.Run("LogValue includes fields", func(t *testing.T) {
+		err := With(errors.New("not found"), "user_id", 42)
+
+		record := logJSON(t, nil, "err", err)
+		group := record["err"].(map[string]any)
+		if group["user_id"] != float64(42) {
+			t.Errorf("user_id = %v, want 42", group["user_id"])
+		}
+	})
+}
@@ -0,0 +1,133 @@
+package errstk
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/tomoemon/go-errstk/internal/importpath"
+)
+
+// DefaultFrameFilter is the filter applied to every stack resolved by errstk.
+// It affects StackFrames, Stack, ErrorStack, %+v and the frames passed to WalkStack
+// callbacks, but not the raw program counters returned by Callers.
+// By default it is nil and all captured frames are kept.
+//
+// Example:
+//
+//	func init() {
+//	    errstk.DefaultFrameFilter = &errstk.FrameFilter{
+//	        DropPackages:   []string{"runtime", "testing"},
+//	        TrimBelow:      []string{"net/http.HandlerFunc.ServeHTTP"},
+//	        CollapseStdlib: true,
+//	    }
+//	}
+//
+// Note: This setting is global and should be set at package initialization time only
+// to avoid race conditions. Frames are resolved once per error, so changing the filter
+// does not affect errors whose frames have already been resolved.
+var DefaultFrameFilter *FrameFilter
+
+// FrameFilter removes uninteresting frames from a stack trace.
+// The rules are applied in the following order: TrimBelow, DropPackages and
+// DropPatterns, then CollapseStdlib.
+type FrameFilter struct {
+	// DropPackages drops frames whose package path is one of these paths
+	// or is nested under one of them (e.g. "net/http" also drops "net/http/httputil").
+	DropPackages []string
+
+	// DropPatterns drops frames whose fully qualified function name
+	// (e.g. "net/http.(*conn).serve") matches one of these regular expressions.
+	DropPatterns []*regexp.Regexp
+
+	// TrimBelow drops every frame below the first frame whose fully qualified
+	// function name (e.g. "net/http.HandlerFunc.ServeHTTP") is in this list.
+	// The boundary frame itself is kept.
+	TrimBelow []string
+
+	// CollapseStdlib replaces each run of two or more consecutive standard library
+	// frames with a single summary frame whose Collapsed field holds the run length.
+	// Packages of the main module are never treated as standard library.
+	CollapseStdlib bool
+}
+
+// Apply returns the frames that remain after applying the filter.
+// The input slice is not modified. A nil filter returns frames unchanged.
+func (ff *FrameFilter) Apply(frames []StackFrame) []StackFrame {
+	if ff == nil || frames == nil {
+		return frames
+	}
+
+	if len(ff.TrimBelow) > 0 {
+		for i, frame := range frames {
+			if slices.Contains(ff.TrimBelow, frame.FullName()) {
+				frames = frames[:i+1]
+				break
+			}
+		}
+	}
+
+	result := make([]StackFrame, 0, len(frames))
+	for _, frame := range frames {
+		if !ff.drops(frame) {
+			result = append(result, frame)
+		}
+	}
+	if ff.CollapseStdlib {
+		result = readBuildModules().collapseStdlib(result)
+	}
+	return result
+}
+
+// collapseStdlib replaces runs of two or more standard library frames with a
+// summary frame. frames is modified in place.
+func (m buildModules) collapseStdlib(frames []StackFrame) []StackFrame {
+	result := frames[:0]
+	for i := 0; i < len(frames); {
+		n := 0
+		for i+n < len(frames) && frames[i+n].Collapsed == 0 && m.isStdlibPackage(frames[i+n].Package) {
+			n++
+		}
+		if n < 2 {
+			// A single frame is kept as is; a summary line would not be shorter.
+			result = append(result, frames[i])
+			i++
+			continue
+		}
+		result = append(result, StackFrame{Package: frames[i].Package, Name: frames[i].Name, Collapsed: n})
+		i += n
+	}
+	return result
+}
+
+// drops reports whether frame is removed by DropPackages or DropPatterns.
+func (ff *FrameFilter) drops(frame StackFrame) bool {
+	for _, pkg := range ff.DropPackages {
+		pkg = strings.TrimSuffix(pkg, "/")
+		if frame.Package == pkg || strings.HasPrefix(frame.Package, pkg+"/") {
+			return true
+		}
+	}
+	if len(ff.DropPatterns) > 0 {
+		name := frame.FullName()
+		for _, re := range ff.DropPatterns {
+			if re.MatchString(name) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// isStdlibPackage reports whether pkg is a standard library package path.
+// Package main and the packages of the main module are excluded before applying
+// importpath.IsStandard, since a module may be declared without a dot, e.g. "module myapp".
+func (m buildModules) isStdlibPackage(pkg string) bool {
+	if pkg == "" || pkg == "main" {
+		return false
+	}
+	if m.main != nil && inModule(pkg, m.main.Path) {
+		return false
+	}
+	return importpath.IsStandard(pkg)
+}
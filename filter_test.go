@@ -0,0 +1,183 @@
+package errstk
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"runtime/debug"
+	"strings"
+	"testing"
+)
+
+// setFrameFilter changes DefaultFrameFilter for the duration of a test.
+func setFrameFilter(t *testing.T, ff *FrameFilter) {
+	t.Helper()
+	saved := DefaultFrameFilter
+	DefaultFrameFilter = ff
+	t.Cleanup(func() {
+		DefaultFrameFilter = saved
+	})
+}
+
+func testFrames(names ...string) []StackFrame {
+	frames := make([]StackFrame, len(names))
+	for i, name := range names {
+		frames[i].Package, frames[i].Name = packageAndName(name)
+		frames[i].File = "/src/" + name + ".go"
+		frames[i].LineNumber = i + 1
+	}
+	return frames
+}
+
+func frameNames(frames []StackFrame) string {
+	names := make([]string, len(frames))
+	for i, frame := range frames {
+		if frame.Collapsed > 0 {
+			names[i] = fmt.Sprintf("...%d", frame.Collapsed)
+		} else {
+			names[i] = frame.FullName()
+		}
+	}
+	return strings.Join(names, ",")
+}
+
+func TestFrameFilter(t *testing.T) {
+	frames := testFrames(
+		"example.com/app.handle",
+		"net/http.HandlerFunc.ServeHTTP",
+		"net/http.(*ServeMux).ServeHTTP",
+		"net/http.serverHandler.ServeHTTP",
+		"net/http.(*conn).serve",
+		"runtime.goexit",
+	)
+
+	tests := []struct {
+		name   string
+		filter *FrameFilter
+		frames []StackFrame
+		want   string
+	}{
+		{
+			name:   "nil filter keeps all frames",
+			filter: nil,
+			frames: frames,
+			want:   "example.com/app.handle,net/http.HandlerFunc.ServeHTTP,net/http.(*ServeMux).ServeHTTP,net/http.serverHandler.ServeHTTP,net/http.(*conn).serve,runtime.goexit",
+		},
+		{
+			name:   "drop by package",
+			filter: &FrameFilter{DropPackages: []string{"runtime", "net"}},
+			frames: frames,
+			want:   "example.com/app.handle",
+		},
+		{
+			name:   "drop by package does not match partial path elements",
+			filter: &FrameFilter{DropPackages: []string{"net/htt", "runtim"}},
+			frames: frames,
+			want:   "example.com/app.handle,net/http.HandlerFunc.ServeHTTP,net/http.(*ServeMux).ServeHTTP,net/http.serverHandler.ServeHTTP,net/http.(*conn).serve,runtime.goexit",
+		},
+		{
+			name:   "drop by regexp",
+			filter: &FrameFilter{DropPatterns: []*regexp.Regexp{regexp.MustCompile(`ServeHTTP$`)}},
+			frames: frames,
+			want:   "example.com/app.handle,net/http.(*conn).serve,runtime.goexit",
+		},
+		{
+			name:   "trim below boundary function",
+			filter: &FrameFilter{TrimBelow: []string{"net/http.HandlerFunc.ServeHTTP"}},
+			frames: frames,
+			want:   "example.com/app.handle,net/http.HandlerFunc.ServeHTTP",
+		},
+		{
+			name:   "trim below unknown function keeps all frames",
+			filter: &FrameFilter{TrimBelow: []string{"example.com/app.unknown"}},
+			frames: frames,
+			want:   "example.com/app.handle,net/http.HandlerFunc.ServeHTTP,net/http.(*ServeMux).ServeHTTP,net/http.serverHandler.ServeHTTP,net/http.(*conn).serve,runtime.goexit",
+		},
+		{
+			name:   "collapse stdlib runs",
+			filter: &FrameFilter{CollapseStdlib: true},
+			frames: testFrames("example.com/app.a", "sort.Slice", "sort.pdqsort", "example.com/app.b", "main.main", "runtime.main", "runtime.goexit"),
+			want:   "example.com/app.a,...2,example.com/app.b,main.main,...2",
+		},
+		{
+			name:   "collapse stdlib keeps a single frame",
+			filter: &FrameFilter{CollapseStdlib: true},
+			frames: testFrames("example.com/app.a", "sort.Slice", "example.com/app.b"),
+			want:   "example.com/app.a,sort.Slice,example.com/app.b",
+		},
+		{
+			name: "rules are combined",
+			filter: &FrameFilter{
+				DropPackages:   []string{"runtime"},
+				TrimBelow:      []string{"net/http.serverHandler.ServeHTTP"},
+				CollapseStdlib: true,
+			},
+			frames: frames,
+			want:   "example.com/app.handle,...3",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := append([]StackFrame(nil), tt.frames...)
+			got := frameNames(tt.filter.Apply(input))
+			if got != tt.want {
+				t.Errorf("Apply() = %s, want %s", got, tt.want)
+			}
+			if frameNames(input) != frameNames(tt.frames) {
+				t.Error("Apply should not modify its input")
+			}
+		})
+	}
+}
+
+func TestCollapseStdlibMainModule(t *testing.T) {
+	mods := buildModules{main: &debug.Module{Path: "myapp"}}
+	frames := testFrames("myapp/internal/db.Query", "myapp.handle", "runtime.main", "runtime.goexit")
+
+	if got, want := frameNames(mods.collapseStdlib(frames)), "myapp/internal/db.Query,myapp.handle,...2"; got != want {
+		t.Errorf("collapseStdlib() = %s, want %s", got, want)
+	}
+}
+
+func TestDefaultFrameFilter(t *testing.T) {
+	t.Run("applies to ErrorStack, %+v and WalkStack", func(t *testing.T) {
+		setFrameFilter(t, &FrameFilter{DropPackages: []string{"runtime", "testing"}})
+		err := With(errors.New("test error"))
+
+		for name, output := range map[string]string{
+			"ErrorStack": ErrorStack(fmt.Errorf("outer: %w", err)),
+			"%+v":        fmt.Sprintf("%+v", err),
+			"Stack":      string(err.(*withStack).Stack()),
+		} {
+			if strings.Contains(output, "runtime.goexit") || strings.Contains(output, "testing.tRunner") {
+				t.Errorf("%s should not contain filtered frames, got:\n%s", name, output)
+			}
+			if !strings.Contains(output, "TestDefaultFrameFilter") {
+				t.Errorf("%s should contain the test frame, got:\n%s", name, output)
+			}
+		}
+
+		WalkStack(err, func(_ error, frames []StackFrame) {
+			for _, frame := range frames {
+				if frame.Package == "runtime" || frame.Package == "testing" {
+					t.Errorf("WalkStack should not receive filtered frame %s", frame.FullName())
+				}
+			}
+		})
+
+		if len(err.(*withStack).Callers()) == len(err.(*withStack).StackFrames()) {
+			t.Error("Callers should still return the raw program counters")
+		}
+	})
+
+	t.Run("collapsed frames are formatted as a summary line", func(t *testing.T) {
+		setFrameFilter(t, &FrameFilter{CollapseStdlib: true})
+		err := With(errors.New("test error"))
+
+		stackTrace := ErrorStack(err)
+		if !strings.Contains(stackTrace, "... 2 standard library frames\n") {
+			t.Errorf("ErrorStack should contain a summary line, got:\n%s", stackTrace)
+		}
+	})
+}
@@ -0,0 +1,145 @@
+package errstk
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+)
+
+// FingerprintOption configures a single call to Fingerprint.
+type FingerprintOption func(*fingerprintOptions)
+
+// fingerprintOptions holds the settings used to compute a fingerprint.
+type fingerprintOptions struct {
+	// limit is the maximum number of frames per stack; 0 means no limit.
+	limit int
+	// keep selects the frames that count; nil keeps all of them.
+	keep func(StackFrame) bool
+	// message includes the message template of the error.
+	message bool
+}
+
+// FingerprintFrameLimit counts only the first n frames of each stack,
+// after FingerprintFrameFilter is applied. n <= 0 means no limit.
+func FingerprintFrameLimit(n int) FingerprintOption {
+	return func(o *fingerprintOptions) {
+		o.limit = n
+	}
+}
+
+// FingerprintFrameFilter counts only the frames for which keep returns true.
+//
+// Example:
+//
+//	// Only frames of your own module
+//	errstk.FingerprintFrameFilter(func(f errstk.StackFrame) bool {
+//	    return strings.HasPrefix(f.Package, "example.com/app")
+//	})
+func FingerprintFrameFilter(keep func(StackFrame) bool) FingerprintOption {
+	return func(o *fingerprintOptions) {
+		o.keep = keep
+	}
+}
+
+// FingerprintMessage includes the message of err in the fingerprint, with numbers,
+// hexadecimal values, UUIDs and quoted strings replaced by placeholders, so that
+// "user 42 not found" and "user 43 not found" share a fingerprint.
+func FingerprintMessage() FingerprintOption {
+	return func(o *fingerprintOptions) {
+		o.message = true
+	}
+}
+
+// Fingerprint returns a stable hash that identifies the kind of failure err represents,
+// for grouping and deduplicating errors across builds.
+// The hash covers the Go types of the errors in the chain, except the errstk wrappers,
+// and the package-qualified function names of the frames of every stack.
+// Line numbers, file paths and program counters are not included, and closure and
+// generic function names are normalized (e.g. "pkg.F.func2" becomes "pkg.F.func"),
+// so the fingerprint survives unrelated code changes.
+// Frames are taken after DefaultFrameFilter is applied; collapsed frames are ignored.
+//
+// Returns an empty string if err is nil.
+//
+// Example:
+//
+//	id := errstk.Fingerprint(err, errstk.FingerprintFrameLimit(5), errstk.FingerprintMessage())
+func Fingerprint(err error, opts ...FingerprintOption) string {
+	if err == nil {
+		return ""
+	}
+	var o fingerprintOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	h := sha256.New()
+	walkChain(err, func(e chainError) bool {
+		// Decoded errors keep the type name of errstk's wrappers that were
+		// not merged into the error they wrap, such as hand-off stacks.
+		switch name := typeName(e.err); name {
+		case withStackTypeName, withFieldsTypeName:
+		default:
+			fmt.Fprintf(h, "type %s\n", name)
+		}
+		return true
+	})
+	if o.message {
+		fmt.Fprintf(h, "message %s\n", messageTemplate(err.Error()))
+	}
+	WalkStack(err, func(err error, frames []StackFrame) {
+		fmt.Fprintf(h, "stack %s\n", captureLabel(err))
+		n := 0
+		for _, frame := range frames {
+			if frame.Collapsed > 0 || (o.keep != nil && !o.keep(frame)) {
+				continue
+			}
+			if o.limit > 0 && n == o.limit {
+				break
+			}
+			fmt.Fprintf(h, "frame %s\n", normalizeFuncName(frame.FullName()))
+			n++
+		}
+	})
+	return hex.EncodeToString(h.Sum(nil)[:16])
+}
+
+var (
+	// withStackTypeName and withFieldsTypeName are the type names of errstk's
+	// wrappers, which do not count towards the fingerprint.
+	withStackTypeName  = fmt.Sprintf("%T", (*withStack)(nil))
+	withFieldsTypeName = fmt.Sprintf("%T", (*withFields)(nil))
+	// closureSuffixPattern matches the compiler-generated suffixes of closures and
+	// go/defer wrappers, such as ".func2", ".func2.1" and ".gowrap1".
+	closureSuffixPattern = regexp.MustCompile(`\.(func|gowrap|deferwrap)\d+(\.\d+)*`)
+	// typeArgsPattern matches the type arguments of a generic function, such as "[...]".
+	typeArgsPattern = regexp.MustCompile(`\[[^\]]*\]`)
+
+	// messagePlaceholders replace the variable parts of a message, in order.
+	messagePlaceholders = []struct {
+		pattern     *regexp.Regexp
+		replacement string
+	}{
+		{regexp.MustCompile(`"(?:[^"\\]|\\.)*"`), `"?"`},
+		{regexp.MustCompile(`'(?:[^'\\]|\\.)*'`), `'?'`},
+		{regexp.MustCompile(`\b[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}\b`), "<uuid>"},
+		{regexp.MustCompile(`\b0[xX][0-9a-fA-F]+\b`), "<hex>"},
+		{regexp.MustCompile(`\d+(\.\d+)?`), "<n>"},
+	}
+)
+
+// normalizeFuncName removes the parts of a fully qualified function name that
+// change with unrelated edits: closure numbering and type arguments.
+func normalizeFuncName(name string) string {
+	name = closureSuffixPattern.ReplaceAllString(name, ".$1")
+	return typeArgsPattern.ReplaceAllString(name, "")
+}
+
+// messageTemplate replaces the variable parts of an error message with placeholders.
+func messageTemplate(msg string) string {
+	for _, p := range messagePlaceholders {
+		msg = p.pattern.ReplaceAllString(msg, p.replacement)
+	}
+	return msg
+}
@@ -0,0 +1,157 @@
+package errstk
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+)
+
+//go:noinline
+func failAt(line int) error {
+	if line == 1 {
+		return With(fmt.Errorf("user %d: %w", 42, os.ErrNotExist))
+	}
+	return With(fmt.Errorf("user %d: %w", 43, os.ErrNotExist))
+}
+
+//go:noinline
+func failElsewhere() error {
+	return With(fmt.Errorf("user %d: %w", 42, os.ErrNotExist))
+}
+
+func TestFingerprint(t *testing.T) {
+	t.Run("nil error", func(t *testing.T) {
+		if got := Fingerprint(nil); got != "" {
+			t.Errorf("Fingerprint(nil) = %q, want empty string", got)
+		}
+	})
+
+	t.Run("ignores line numbers and messages by default", func(t *testing.T) {
+		a, b := Fingerprint(failAt(1)), Fingerprint(failAt(2))
+		if a != b {
+			t.Errorf("fingerprints differ: %s != %s", a, b)
+		}
+		if len(a) != 32 {
+			t.Errorf("fingerprint %q should be 32 hex digits", a)
+		}
+	})
+
+	t.Run("differs by function", func(t *testing.T) {
+		if Fingerprint(failAt(1)) == Fingerprint(failElsewhere()) {
+			t.Error("errors from different functions should have different fingerprints")
+		}
+	})
+
+	t.Run("differs by error type", func(t *testing.T) {
+		a := Fingerprint(failAt(1))
+		b := Fingerprint(fmt.Errorf("outer: %w", failAt(1)))
+		if a == b {
+			t.Error("an extra fmt.Errorf wrapper should change the fingerprint")
+		}
+		if c := Fingerprint(With(failAt(1), "user_id", 42)); a != c {
+			t.Error("errstk wrappers should not change the fingerprint")
+		}
+	})
+
+	t.Run("message template", func(t *testing.T) {
+		a := Fingerprint(failAt(1), FingerprintMessage())
+		b := Fingerprint(failAt(2), FingerprintMessage())
+		if a != b {
+			t.Error("messages differing only in numbers should share a fingerprint")
+		}
+		c := Fingerprint(With(errors.New("other")), FingerprintMessage())
+		d := Fingerprint(With(errors.New("another")), FingerprintMessage())
+		if c == d {
+			t.Error("different messages should have different fingerprints")
+		}
+	})
+
+	t.Run("frame limit and filter", func(t *testing.T) {
+		a := Fingerprint(failAt(1), FingerprintFrameLimit(1))
+		b := Fingerprint(fmt.Errorf("outer: %w", failAt(1)), FingerprintFrameLimit(1))
+		if a == b {
+			t.Error("frame limit should not hide type differences")
+		}
+
+		onlyRuntime := FingerprintFrameFilter(func(f StackFrame) bool {
+			return f.Package == "runtime"
+		})
+		if Fingerprint(failAt(1), onlyRuntime) != Fingerprint(failElsewhere(), onlyRuntime) {
+			t.Error("frames rejected by the filter should not count")
+		}
+		if Fingerprint(failAt(1), FingerprintFrameLimit(0)) != Fingerprint(failAt(1)) {
+			t.Error("FingerprintFrameLimit(0) should mean no limit")
+		}
+	})
+
+	t.Run("survives a JSON round trip", func(t *testing.T) {
+		err := fmt.Errorf("handler: %w", failAt(1))
+		data, mErr := MarshalJSON(err)
+		if mErr != nil {
+			t.Fatalf("MarshalJSON returned error: %v", mErr)
+		}
+		decoded, uErr := UnmarshalJSON(data)
+		if uErr != nil {
+			t.Fatalf("UnmarshalJSON returned error: %v", uErr)
+		}
+		if Fingerprint(decoded) != Fingerprint(err) {
+			t.Error("decoded error should have the same fingerprint")
+		}
+	})
+
+	t.Run("survives a JSON round trip with hand-off stacks", func(t *testing.T) {
+		err := Rethrow(fmt.Errorf("handler: %w", failAt(1)))
+		data, mErr := MarshalJSON(err)
+		if mErr != nil {
+			t.Fatalf("MarshalJSON returned error: %v", mErr)
+		}
+		decoded, uErr := UnmarshalJSON(data)
+		if uErr != nil {
+			t.Fatalf("UnmarshalJSON returned error: %v", uErr)
+		}
+		if Fingerprint(decoded) != Fingerprint(err) {
+			t.Error("decoded error should have the same fingerprint")
+		}
+	})
+}
+
+func TestNormalizeFuncName(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"example.com/app.handle", "example.com/app.handle"},
+		{"example.com/app.handle.func2", "example.com/app.handle.func"},
+		{"example.com/app.handle.func2.1", "example.com/app.handle.func"},
+		{"example.com/app.(*Server).run.gowrap1", "example.com/app.(*Server).run.gowrap"},
+		{"example.com/app.Map[...]", "example.com/app.Map"},
+		{"example.com/app.Map[go.shape.int].func1", "example.com/app.Map.func"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := normalizeFuncName(tt.name); got != tt.want {
+				t.Errorf("normalizeFuncName(%q) = %q, want %q", tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMessageTemplate(t *testing.T) {
+	tests := []struct {
+		msg  string
+		want string
+	}{
+		{"user 42 not found", "user <n> not found"},
+		{`open "/tmp/a.txt": no such file`, `open "?": no such file`},
+		{"bad id 123e4567-e89b-12d3-a456-426614174000", "bad id <uuid>"},
+		{"pointer 0xc000012345 took 1.5s", "pointer <hex> took <n>s"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.msg, func(t *testing.T) {
+			if got := messageTemplate(tt.msg); got != tt.want {
+				t.Errorf("messageTemplate(%q) = %q, want %q", tt.msg, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package errstk
+
+import "sync"
+
+// DefaultFrameCacheSize is the maximum number of resolved program counters kept in
+// the process-wide frame cache shared by all errors.
+// Typically this should remain at 4096, which covers the hot error paths of most programs.
+// Set it to 0 to disable the cache.
+// Advanced users can set this at package initialization time if needed.
+var DefaultFrameCacheSize = 4096
+
+// frameCache is the process-wide cache of resolved stack frames.
+var frameCache pcFrameCache
+
+// pcFrameCache memoizes the StackFrame resolved for each program counter.
+// It is safe for concurrent use and holds at most DefaultFrameCacheSize entries;
+// when full, an arbitrary entry is evicted to make room for a new one.
+type pcFrameCache struct {
+	mu     sync.RWMutex
+	frames map[uintptr]StackFrame
+}
+
+// lookup returns the StackFrame for pc, resolving and caching it if needed.
+func (c *pcFrameCache) lookup(pc uintptr) StackFrame {
+	limit := DefaultFrameCacheSize
+	if limit <= 0 {
+		return newStackFrame(pc)
+	}
+
+	c.mu.RLock()
+	frame, ok := c.frames[pc]
+	c.mu.RUnlock()
+	if ok {
+		return frame
+	}
+
+	frame = newStackFrame(pc)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.frames == nil {
+		c.frames = make(map[uintptr]StackFrame)
+	}
+	for k := range c.frames {
+		if len(c.frames) < limit {
+			break
+		}
+		delete(c.frames, k)
+	}
+	c.frames[pc] = frame
+	return frame
+}
+
+// len returns the number of cached frames.
+func (c *pcFrameCache) len() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return len(c.frames)
+}
+
+// reset removes all cached frames.
+func (c *pcFrameCache) reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.frames = nil
+}
@@ -0,0 +1,149 @@
+package errstk
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+)
+
+// setFrameCacheSize changes DefaultFrameCacheSize for the duration of a test or benchmark.
+func setFrameCacheSize(tb testing.TB, size int) {
+	tb.Helper()
+	saved := DefaultFrameCacheSize
+	DefaultFrameCacheSize = size
+	frameCache.reset()
+	tb.Cleanup(func() {
+		DefaultFrameCacheSize = saved
+		frameCache.reset()
+	})
+}
+
+func TestFrameCache(t *testing.T) {
+	t.Run("StackFrames is memoized per error", func(t *testing.T) {
+		err := With(errors.New("test error")).(*withStack)
+
+		first := err.stackFrames()
+		second := err.stackFrames()
+		if &first[0] != &second[0] {
+			t.Error("stackFrames should return the memoized slice")
+		}
+	})
+
+	t.Run("StackFrames returns a copy", func(t *testing.T) {
+		err := With(errors.New("test error")).(*withStack)
+
+		frames := err.StackFrames()
+		frames[0].Name = "modified"
+		if err.StackFrames()[0].Name == "modified" {
+			t.Error("modifying the returned frames should not affect the error")
+		}
+	})
+
+	t.Run("cached frames match resolved frames", func(t *testing.T) {
+		setFrameCacheSize(t, 4096)
+		err := With(errors.New("test error")).(*withStack)
+
+		for i, pc := range err.stack {
+			want := newStackFrame(pc)
+			if got := frameCache.lookup(pc); got != want {
+				t.Errorf("frame %d = %+v, want %+v", i, got, want)
+			}
+		}
+		if frameCache.len() == 0 {
+			t.Error("frame cache should not be empty")
+		}
+	})
+
+	t.Run("cache size is bounded", func(t *testing.T) {
+		setFrameCacheSize(t, 2)
+		err := With(errors.New("test error")).(*withStack)
+		if len(err.stack) <= 2 {
+			t.Skip("stack is too short to exceed the cache size")
+		}
+
+		err.StackFrames()
+		if n := frameCache.len(); n > 2 {
+			t.Errorf("frame cache has %d entries, want at most 2", n)
+		}
+	})
+
+	t.Run("zero size disables the cache", func(t *testing.T) {
+		setFrameCacheSize(t, 0)
+
+		With(errors.New("test error")).(*withStack).StackFrames()
+		if n := frameCache.len(); n != 0 {
+			t.Errorf("frame cache has %d entries, want 0", n)
+		}
+	})
+
+	t.Run("concurrent access", func(t *testing.T) {
+		setFrameCacheSize(t, 8)
+		err := With(errors.New("test error"))
+
+		var wg sync.WaitGroup
+		for range 8 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for range 100 {
+					_ = ErrorStack(err)
+					_ = ErrorStack(With(errors.New("another")))
+				}
+			}()
+		}
+		wg.Wait()
+	})
+}
+
+func BenchmarkWith(b *testing.B) {
+	baseErr := errors.New("test error")
+	for _, size := range []int{0, DefaultFrameCacheSize} {
+		b.Run(fmt.Sprintf("cache=%d", size), func(b *testing.B) {
+			setFrameCacheSize(b, size)
+			b.ReportAllocs()
+			for b.Loop() {
+				_ = With(baseErr).(*withStack).StackFrames()
+			}
+		})
+	}
+}
+
+func BenchmarkWrap(b *testing.B) {
+	baseErr := errors.New("test error")
+	f := func() (err error) {
+		defer Wrap(&err)
+		return baseErr
+	}
+	for _, size := range []int{0, DefaultFrameCacheSize} {
+		b.Run(fmt.Sprintf("cache=%d", size), func(b *testing.B) {
+			setFrameCacheSize(b, size)
+			b.ReportAllocs()
+			for b.Loop() {
+				_ = f().(*withStack).StackFrames()
+			}
+		})
+	}
+}
+
+func BenchmarkErrorStack(b *testing.B) {
+	newErr := func() error {
+		return fmt.Errorf("outer: %w", errors.Join(With(errors.New("error 1")), With(errors.New("error 2"))))
+	}
+	b.Run("same error", func(b *testing.B) {
+		err := newErr()
+		b.ReportAllocs()
+		for b.Loop() {
+			_ = ErrorStack(err)
+		}
+	})
+	for _, size := range []int{0, DefaultFrameCacheSize} {
+		b.Run(fmt.Sprintf("new error/cache=%d", size), func(b *testing.B) {
+			setFrameCacheSize(b, size)
+			b.ReportAllocs()
+			for b.Loop() {
+				_ = ErrorStack(newErr())
+			}
+		})
+	}
+}
@@ -0,0 +1,100 @@
+package errstk
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// Group runs goroutines like golang.org/x/sync/errgroup.Group, but keeps every
+// failure instead of only the first one.
+// Each error returned by a goroutine is wrapped with a "spawned at" stack
+// recording where Go was called. An error that already has a stack keeps it.
+// A plain error is not given a stack of its goroutine: by the time f has
+// returned, that stack would only show Group's own frames above runtime.goexit,
+// so the "spawned at" stack is the only one. Return errors wrapped with With or
+// Wrap from f to record where they happened.
+// Panics in the goroutines are recovered and reported as *PanicError.
+//
+// A zero Group is valid and does not cancel on error.
+//
+// Example:
+//
+//	g, ctx := errstk.WithContext(ctx)
+//	for _, url := range urls {
+//	    g.Go(func() error {
+//	        return fetch(ctx, url)
+//	    })
+//	}
+//	if err := g.Wait(); err != nil {
+//	    log.Print(errstk.ErrorStack(err))
+//	}
+type Group struct {
+	cancel func(error)
+	wg     sync.WaitGroup
+
+	mu   sync.Mutex
+	errs []error
+}
+
+// WithContext returns a new Group and an associated Context derived from ctx.
+// The derived Context is canceled the first time a function passed to Go returns
+// a non-nil error or panics, or the first time Wait returns, whichever occurs first.
+// context.Cause reports the first error.
+func WithContext(ctx context.Context) (*Group, context.Context) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	return &Group{cancel: cancel}, ctx
+}
+
+// Go calls f in a new goroutine.
+// The stack at the call to Go is recorded and attached to the error returned by f
+// as a "spawned at" stack.
+//
+//go:noinline
+func (g *Group) Go(f func() error) {
+	// Skip 3 frames: Go -> callers -> runtime.Callers
+	const innerSkip = 3
+	spawned := callers(DefaultSkipFrames+innerSkip, DefaultMaxStackDepth)
+
+	g.wg.Add(1)
+	go func() {
+		defer g.wg.Done()
+		if err := run(f); err != nil {
+			g.addError(&withStack{error: err, stack: spawned, kind: kindSpawned})
+		}
+	}()
+}
+
+// Wait blocks until all goroutines started by Go have returned, then returns
+// an errors.Join of every error they returned, in the order they failed.
+// Returns nil if all of them succeeded.
+func (g *Group) Wait() error {
+	g.wg.Wait()
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	err := errors.Join(g.errs...)
+	if g.cancel != nil {
+		g.cancel(err)
+	}
+	return err
+}
+
+// addError records err and cancels the context on the first failure.
+func (g *Group) addError(err error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.errs = append(g.errs, err)
+	if len(g.errs) == 1 && g.cancel != nil {
+		g.cancel(err)
+	}
+}
+
+// run calls f, converting a panic into a *PanicError.
+// A stack captured here, after f has returned, would only show the frames of
+// Group itself, so a plain error is left to the "spawned at" stack.
+//
+//go:noinline
+func run(f func() error) (err error) {
+	defer Recover(&err)
+	return f()
+}
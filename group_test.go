@@ -0,0 +1,109 @@
+package errstk
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestGroup(t *testing.T) {
+	t.Run("zero Group without errors", func(t *testing.T) {
+		var g Group
+		results := make([]int, 3)
+		for i := range results {
+			g.Go(func() error {
+				results[i] = i + 1
+				return nil
+			})
+		}
+		if err := g.Wait(); err != nil {
+			t.Fatalf("Wait returned error: %v", err)
+		}
+		if results[0] != 1 || results[1] != 2 || results[2] != 3 {
+			t.Errorf("results = %v, want [1 2 3]", results)
+		}
+	})
+
+	t.Run("Wait returns every failure", func(t *testing.T) {
+		var g Group
+		errA := errors.New("error A")
+		errB := errors.New("error B")
+		g.Go(func() error { return errA })
+		g.Go(func() error { return With(errB) })
+		g.Go(func() error { return nil })
+
+		err := g.Wait()
+		if !errors.Is(err, errA) || !errors.Is(err, errB) {
+			t.Fatalf("Wait should return both errors, got %v", err)
+		}
+
+		var stacks, spawned int
+		WalkStack(err, func(err error, _ []StackFrame) {
+			if captureLabel(err) == "spawned at" {
+				spawned++
+			} else {
+				stacks++
+			}
+		})
+		if stacks != 1 || spawned != 2 {
+			t.Errorf("WalkStack found %d stacks and %d spawned-at stacks, want 1 and 2", stacks, spawned)
+		}
+
+		stackTrace := ErrorStack(err)
+		if strings.Count(stackTrace, "spawned at:\n") != 2 {
+			t.Errorf("ErrorStack should contain 2 spawned-at stacks, got:\n%s", stackTrace)
+		}
+		if !strings.Contains(stackTrace, "spawned at:\ngithub.com/tomoemon/go-errstk.TestGroup.func2()\n") {
+			t.Errorf("spawned-at stack should start at the caller of Go, got:\n%s", stackTrace)
+		}
+		if !strings.Contains(stackTrace, "error A\nspawned at:\n") {
+			t.Errorf("spawned-at stack of a plain error should be preceded by its message, got:\n%s", stackTrace)
+		}
+	})
+
+	t.Run("panics are recovered", func(t *testing.T) {
+		var g Group
+		g.Go(func() error {
+			panic("boom")
+		})
+
+		err := g.Wait()
+		var panicErr *PanicError
+		if !errors.As(err, &panicErr) {
+			t.Fatalf("Wait should return a *PanicError, got %v", err)
+		}
+		if panicErr.Value != "boom" {
+			t.Errorf("Value = %v, want boom", panicErr.Value)
+		}
+	})
+
+	t.Run("WithContext cancels on the first error", func(t *testing.T) {
+		g, ctx := WithContext(context.Background())
+		errFirst := errors.New("first")
+		g.Go(func() error { return errFirst })
+		g.Go(func() error {
+			<-ctx.Done()
+			return ctx.Err()
+		})
+
+		err := g.Wait()
+		if !errors.Is(err, errFirst) || !errors.Is(err, context.Canceled) {
+			t.Errorf("Wait should return both errors, got %v", err)
+		}
+		if cause := context.Cause(ctx); !errors.Is(cause, errFirst) {
+			t.Errorf("context.Cause = %v, want first error", cause)
+		}
+	})
+
+	t.Run("WithContext cancels when Wait returns", func(t *testing.T) {
+		g, ctx := WithContext(context.Background())
+		g.Go(func() error { return nil })
+		if err := g.Wait(); err != nil {
+			t.Fatalf("Wait returned error: %v", err)
+		}
+		if ctx.Err() == nil {
+			t.Error("context should be canceled after Wait returns")
+		}
+	})
+}
@@ -0,0 +1,125 @@
+// Package buildid reads the Go build ID of an executable, as printed by
+// "go tool buildid". It is shared by errstk and errstk-symbolize, so that both
+// find the same ID in the same binary.
+package buildid
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+)
+
+// Markers of the Go build ID, taken from cmd/internal/buildid.
+var (
+	elfMagic      = []byte("\x7fELF")
+	goBuildPrefix = []byte("\xff Go build ID: \"")
+	goBuildEnd    = []byte("\"\n \xff")
+	elfGoNoteName = []byte("Go\x00\x00")
+)
+
+const (
+	elfGoBuildIDTag = 4
+	elfPTNote       = 4
+	buildIDReadSize = 32 * 1024
+)
+
+// Read returns the Go build ID of the executable r, or an empty string if none
+// is found. ELF binaries store it in a note; other formats store it near the
+// start of the text segment.
+func Read(r io.ReaderAt) string {
+	data := make([]byte, buildIDReadSize)
+	n, err := r.ReadAt(data, 0)
+	if err != nil && err != io.EOF {
+		return ""
+	}
+	data = data[:n]
+	if bytes.HasPrefix(data, elfMagic) {
+		if id := readELFBuildID(r, data); id != "" {
+			return id
+		}
+	}
+	_, rest, ok := bytes.Cut(data, goBuildPrefix)
+	if !ok {
+		return ""
+	}
+	id, _, ok := bytes.Cut(rest, goBuildEnd)
+	if !ok {
+		return ""
+	}
+	return string(id)
+}
+
+// readELFBuildID returns the Go build ID from the notes of the ELF file r,
+// whose first bytes are header.
+func readELFBuildID(r io.ReaderAt, header []byte) string {
+	if len(header) < 52 {
+		return ""
+	}
+	var order binary.ByteOrder = binary.LittleEndian
+	if header[5] == 2 {
+		order = binary.BigEndian
+	}
+	is64 := header[4] == 2
+
+	var phoff uint64
+	var phentsize, phnum int
+	if is64 {
+		if len(header) < 64 {
+			return ""
+		}
+		phoff = order.Uint64(header[0x20:])
+		phentsize, phnum = int(order.Uint16(header[0x36:])), int(order.Uint16(header[0x38:]))
+	} else {
+		phoff = uint64(order.Uint32(header[0x1c:]))
+		phentsize, phnum = int(order.Uint16(header[0x2a:])), int(order.Uint16(header[0x2c:]))
+	}
+
+	if phentsize < 32 {
+		return ""
+	}
+	ph := make([]byte, phentsize)
+	for i := range phnum {
+		if _, err := r.ReadAt(ph, int64(phoff)+int64(i*phentsize)); err != nil {
+			return ""
+		}
+		if order.Uint32(ph) != elfPTNote {
+			continue
+		}
+		var off, size uint64
+		if is64 {
+			off, size = order.Uint64(ph[0x08:]), order.Uint64(ph[0x20:])
+		} else {
+			off, size = uint64(order.Uint32(ph[0x04:])), uint64(order.Uint32(ph[0x10:]))
+		}
+		if size > buildIDReadSize {
+			continue
+		}
+		notes := make([]byte, size)
+		if _, err := r.ReadAt(notes, int64(off)); err != nil {
+			continue
+		}
+		if id := FromNotes(notes, order); id != "" {
+			return id
+		}
+	}
+	return ""
+}
+
+// FromNotes returns the Go build ID stored in the contents of an ELF note segment
+// or section, or an empty string if notes holds no Go build ID note.
+func FromNotes(notes []byte, order binary.ByteOrder) string {
+	align := func(n int) int { return (n + 3) &^ 3 }
+	for len(notes) >= 12 {
+		namesz, descsz, tag := int(order.Uint32(notes)), int(order.Uint32(notes[4:])), order.Uint32(notes[8:])
+		notes = notes[12:]
+		if align(namesz)+align(descsz) > len(notes) {
+			return ""
+		}
+		name, desc := notes[:namesz], notes[align(namesz):align(namesz)+descsz]
+		if tag == elfGoBuildIDTag && bytes.Equal(name, elfGoNoteName) {
+			return string(desc)
+		}
+		notes = notes[align(namesz)+align(descsz):]
+	}
+	return ""
+}
@@ -0,0 +1,56 @@
+package buildid
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+func TestRead(t *testing.T) {
+	t.Run("raw marker", func(t *testing.T) {
+		data := []byte("\x00\x01\xff Go build ID: \"abc/def\"\n \xff\x00")
+		if got := Read(bytes.NewReader(data)); got != "abc/def" {
+			t.Errorf("Read() = %q, want %q", got, "abc/def")
+		}
+	})
+
+	t.Run("ELF note", func(t *testing.T) {
+		if got := Read(bytes.NewReader(elfWithGoNote("abc/def"))); got != "abc/def" {
+			t.Errorf("Read() = %q, want %q", got, "abc/def")
+		}
+	})
+
+	t.Run("no build ID", func(t *testing.T) {
+		if got := Read(bytes.NewReader([]byte("not a binary"))); got != "" {
+			t.Errorf("Read() = %q, want empty", got)
+		}
+	})
+}
+
+// elfWithGoNote returns a minimal 64-bit little-endian ELF image with one
+// PT_NOTE segment holding a GNU note followed by the Go build ID note.
+func elfWithGoNote(id string) []byte {
+	var notes bytes.Buffer
+	writeNote := func(name string, tag uint32, desc string) {
+		binary.Write(&notes, binary.LittleEndian, []uint32{uint32(len(name)), uint32(len(desc)), tag})
+		notes.WriteString(name)
+		notes.Write(make([]byte, (4-len(name)%4)%4))
+		notes.WriteString(desc)
+		notes.Write(make([]byte, (4-len(desc)%4)%4))
+	}
+	writeNote("GNU\x00", 3, "0123456789abcdef")
+	writeNote("Go\x00\x00", elfGoBuildIDTag, id)
+
+	const headerSize, phSize = 64, 56
+	image := make([]byte, headerSize+phSize)
+	copy(image, elfMagic)
+	image[4], image[5] = 2, 1 // ELFCLASS64, ELFDATA2LSB
+	binary.LittleEndian.PutUint64(image[0x20:], headerSize)
+	binary.LittleEndian.PutUint16(image[0x36:], phSize)
+	binary.LittleEndian.PutUint16(image[0x38:], 1)
+	ph := image[headerSize:]
+	binary.LittleEndian.PutUint32(ph, elfPTNote)
+	binary.LittleEndian.PutUint64(ph[0x08:], uint64(len(image)))
+	binary.LittleEndian.PutUint64(ph[0x20:], uint64(notes.Len()))
+	return append(image, notes.Bytes()...)
+}
@@ -0,0 +1,17 @@
+// Package importpath holds helpers for Go import paths shared by errstk and errstklint.
+package importpath
+
+import "strings"
+
+// IsStandard reports whether path looks like a standard library package path.
+// Standard library paths have no dot in their first element, while module paths
+// usually do. The heuristic cannot tell package main or a main module declared
+// without a dot (e.g. "module myapp") apart from the standard library; callers
+// that may see those must exclude them first.
+func IsStandard(path string) bool {
+	if path == "" {
+		return false
+	}
+	first, _, _ := strings.Cut(path, "/")
+	return !strings.Contains(first, ".")
+}
@@ -0,0 +1,22 @@
+package importpath
+
+import "testing"
+
+func TestIsStandard(t *testing.T) {
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{"fmt", true},
+		{"net/http", true},
+		{"github.com/tomoemon/go-errstk", false},
+		{"golang.org/x/sync/errgroup", false},
+		{"example.com", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := IsStandard(tt.path); got != tt.want {
+			t.Errorf("IsStandard(%q) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+}
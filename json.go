@@ -0,0 +1,265 @@
+package errstk
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+)
+
+// JSONError is the serialized form of an error tree.
+// Each node describes one error in the chain; fmt.Errorf wrappers have a single
+// child and errors.Join values have one child per joined error.
+//
+// A node created from an errstk stack wrapper is merged with the error it wraps,
+// so the stack and fields appear as Frames and Fields on the node of the wrapped error.
+// Stacks attached by Rethrow, Group and Go are kept as separate nodes with a Label.
+type JSONError struct {
+	Message string         `json:"message"`
+	Type    string         `json:"type"`
+	Fields  map[string]any `json:"fields,omitempty"`
+	Frames  []JSONFrame    `json:"frames,omitempty"`
+	// Label describes why Frames were captured ("rethrown at", "spawned at" or "created by").
+	// It is empty for the stack captured where the error was created or first wrapped.
+	Label    string       `json:"label,omitempty"`
+	Children []*JSONError `json:"children,omitempty"`
+}
+
+// JSONFrame is the serialized form of a StackFrame.
+type JSONFrame struct {
+	Package  string  `json:"package"`
+	Function string  `json:"function"`
+	File     string  `json:"file"`
+	Line     int     `json:"line"`
+	PC       uintptr `json:"pc"`
+	// Inlined is set on frames of functions the compiler inlined into their caller.
+	Inlined bool `json:"inlined,omitempty"`
+	// Collapsed is set on summary frames created by FrameFilter.CollapseStdlib.
+	Collapsed int `json:"collapsed,omitempty"`
+}
+
+// NewJSONError builds the serializable tree for err.
+// Returns nil if err is nil.
+func NewJSONError(err error) *JSONError {
+	if err == nil {
+		return nil
+	}
+	switch w := err.(type) {
+	case *withStack:
+		node := NewJSONError(w.error)
+		if node.Frames == nil && w.kind == kindOrigin {
+			node.Frames = newJSONFrames(w.stackFrames())
+			node.addFields(w.fields)
+			return node
+		}
+		// The wrapped error carries its own stack, or this is a hand-off stack;
+		// keep both as separate nodes.
+		node = &JSONError{
+			Message:  w.Error(),
+			Type:     typeName(w),
+			Frames:   newJSONFrames(w.stackFrames()),
+			Label:    w.kind.label(),
+			Children: []*JSONError{node},
+		}
+		node.addFields(w.fields)
+		return node
+	case *withFields:
+		node := NewJSONError(w.error)
+		node.addFields(w.fields)
+		return node
+	}
+
+	node := &JSONError{
+		Message: err.Error(),
+		Type:    typeName(err),
+		Label:   captureLabel(err),
+	}
+	if f, ok := err.(interface{ errorFields() []Field }); ok {
+		node.addFields(f.errorFields())
+	}
+	if frames, ok := stackFramesOf(err); ok {
+		node.Frames = newJSONFrames(frames)
+	}
+	if u, ok := err.(interface{ Unwrap() []error }); ok {
+		for _, e := range u.Unwrap() {
+			if e != nil {
+				node.Children = append(node.Children, NewJSONError(e))
+			}
+		}
+	} else if u := errors.Unwrap(err); u != nil {
+		node.Children = []*JSONError{NewJSONError(u)}
+	}
+	return node
+}
+
+// Err rebuilds a read-only error value from the serialized tree.
+// The returned error reports the original message from Error(), exposes its
+// frames through StackFrames() and its children through Unwrap() []error,
+// so WalkStack and ErrorStack can traverse it.
+// Only the messages and the shape of the chain are preserved: the original Go
+// types and sentinel values are not restored, so errors.Is and errors.As do not
+// match the original errors. The type name is kept for re-encoding.
+func (e *JSONError) Err() error {
+	if e == nil {
+		return nil
+	}
+	decoded := &decodedError{
+		msg:      e.Message,
+		typeName: e.Type,
+		label:    e.Label,
+	}
+	for _, key := range slices.Sorted(maps.Keys(e.Fields)) {
+		decoded.fields = append(decoded.fields, Field{Key: key, Value: e.Fields[key]})
+	}
+	if e.Frames != nil {
+		decoded.frames = make([]StackFrame, len(e.Frames))
+		for i, f := range e.Frames {
+			decoded.frames[i] = f.stackFrame()
+		}
+	}
+	for _, c := range e.Children {
+		if c != nil {
+			decoded.errs = append(decoded.errs, c.Err())
+		}
+	}
+	return decoded
+}
+
+// MarshalJSON serializes the whole error tree of err, including fmt.Errorf
+// chains and errors.Join branches, into the JSONError schema.
+// Returns "null" if err is nil.
+//
+// Example:
+//
+//	data, err := errstk.MarshalJSON(err)
+func MarshalJSON(err error) ([]byte, error) {
+	return json.Marshal(NewJSONError(err))
+}
+
+// UnmarshalJSON decodes data produced by MarshalJSON and rebuilds a read-only
+// error value. See JSONError.Err for the behavior of the returned error.
+// Returns a nil error value if data is "null".
+//
+// Example:
+//
+//	remoteErr, err := errstk.UnmarshalJSON(data)
+//	if err != nil {
+//	    return err
+//	}
+//	fmt.Println(errstk.ErrorStack(remoteErr))
+func UnmarshalJSON(data []byte) (error, error) {
+	var node *JSONError
+	if err := json.Unmarshal(data, &node); err != nil {
+		return nil, err
+	}
+	return node.Err(), nil
+}
+
+// MarshalJSON satisfies the json.Marshaler interface
+// so that the error and its stack can be serialized directly.
+func (w *withStack) MarshalJSON() ([]byte, error) {
+	return MarshalJSON(w)
+}
+
+// decodedError is the error value rebuilt by JSONError.Err.
+type decodedError struct {
+	msg      string
+	typeName string
+	label    string
+	fields   []Field
+	frames   []StackFrame
+	errs     []error
+}
+
+func (e *decodedError) Error() string {
+	return e.msg
+}
+
+// StackFrames returns the frames decoded from the serialized error.
+func (e *decodedError) StackFrames() []StackFrame {
+	return e.frames
+}
+
+func (e *decodedError) errorFields() []Field {
+	return e.fields
+}
+
+// Unwrap returns the decoded children of this error.
+func (e *decodedError) Unwrap() []error {
+	return e.errs
+}
+
+// typeName returns the concrete Go type of err, preserving the original
+// type name of decoded errors.
+func typeName(err error) string {
+	if d, ok := err.(*decodedError); ok {
+		return d.typeName
+	}
+	return fmt.Sprintf("%T", err)
+}
+
+// stackFrame converts the serialized frame back into a StackFrame.
+func (f JSONFrame) stackFrame() StackFrame {
+	return StackFrame{
+		File:           f.File,
+		LineNumber:     f.Line,
+		Name:           f.Function,
+		Package:        f.Package,
+		ProgramCounter: f.PC,
+		Inlined:        f.Inlined,
+		Collapsed:      f.Collapsed,
+	}
+}
+
+// addFields sets fields on the node; a later value overwrites an earlier one with the same key.
+// A value that encoding/json cannot encode is stored as its fmt.Sprint form,
+// so a single field never makes the whole tree fail to marshal.
+func (e *JSONError) addFields(fields []Field) {
+	if len(fields) == 0 {
+		return
+	}
+	if e.Fields == nil {
+		e.Fields = make(map[string]any, len(fields))
+	}
+	for _, f := range fields {
+		e.Fields[f.Key] = jsonValue(f.Value)
+	}
+}
+
+// JSONFields returns the fields of the whole chain of err, as collected by Fields,
+// in the form used by JSONError.Fields: a map in which a later field overwrites an
+// earlier one with the same key, and values that encoding/json cannot encode are
+// replaced by their fmt.Sprint form. Returns nil if there are no fields.
+func JSONFields(err error) map[string]any {
+	var node JSONError
+	node.addFields(Fields(err))
+	return node.Fields
+}
+
+// jsonValue returns v if encoding/json can encode it, or fmt.Sprint(v) otherwise.
+func jsonValue(v any) any {
+	if _, err := json.Marshal(v); err != nil {
+		return fmt.Sprint(v)
+	}
+	return v
+}
+
+func newJSONFrames(frames []StackFrame) []JSONFrame {
+	if frames == nil {
+		return nil
+	}
+	result := make([]JSONFrame, len(frames))
+	for i, f := range frames {
+		result[i] = JSONFrame{
+			Package:   f.Package,
+			Function:  f.Name,
+			File:      f.File,
+			Line:      f.LineNumber,
+			PC:        f.ProgramCounter,
+			Inlined:   f.Inlined,
+			Collapsed: f.Collapsed,
+		}
+	}
+	return result
+}
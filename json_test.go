@@ -0,0 +1,262 @@
+package errstk
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestMarshalJSON(t *testing.T) {
+	t.Run("nil error marshals to null", func(t *testing.T) {
+		data, err := MarshalJSON(nil)
+		if err != nil {
+			t.Fatalf("MarshalJSON(nil) returned error: %v", err)
+		}
+		if string(data) != "null" {
+			t.Errorf("MarshalJSON(nil) = %s, want null", data)
+		}
+	})
+
+	t.Run("error with stack includes frames", func(t *testing.T) {
+		err := With(errors.New("test error"))
+
+		data, mErr := MarshalJSON(err)
+		if mErr != nil {
+			t.Fatalf("MarshalJSON returned error: %v", mErr)
+		}
+
+		var node JSONError
+		if err := json.Unmarshal(data, &node); err != nil {
+			t.Fatalf("failed to unmarshal: %v", err)
+		}
+		if node.Message != "test error" {
+			t.Errorf("Message = %q, want %q", node.Message, "test error")
+		}
+		if node.Type != "*errors.errorString" {
+			t.Errorf("Type = %q, want %q", node.Type, "*errors.errorString")
+		}
+		if len(node.Frames) == 0 {
+			t.Fatal("Frames should not be empty")
+		}
+		first := node.Frames[0]
+		if first.Package != "github.com/tomoemon/go-errstk" {
+			t.Errorf("Package = %q, want %q", first.Package, "github.com/tomoemon/go-errstk")
+		}
+		if !strings.HasPrefix(first.Function, "TestMarshalJSON") {
+			t.Errorf("Function = %q, want prefix TestMarshalJSON", first.Function)
+		}
+		if !strings.HasSuffix(first.File, "json_test.go") {
+			t.Errorf("File = %q, want suffix json_test.go", first.File)
+		}
+		if first.Line == 0 || first.PC == 0 {
+			t.Errorf("Line and PC should be set, got line=%d pc=%d", first.Line, first.PC)
+		}
+	})
+
+	t.Run("withStack implements json.Marshaler", func(t *testing.T) {
+		err := With(errors.New("test error"))
+
+		direct, mErr := json.Marshal(err)
+		if mErr != nil {
+			t.Fatalf("json.Marshal returned error: %v", mErr)
+		}
+		if !strings.Contains(string(direct), `"frames":[`) {
+			t.Errorf("json.Marshal output should contain frames, got: %s", direct)
+		}
+	})
+
+	t.Run("fields that cannot be encoded fall back to fmt.Sprint", func(t *testing.T) {
+		ch := make(chan int)
+		err := With(errors.New("test error"), "user_id", 42, "ch", ch)
+
+		data, mErr := json.Marshal(err)
+		if mErr != nil {
+			t.Fatalf("json.Marshal returned error: %v", mErr)
+		}
+		var node JSONError
+		if err := json.Unmarshal(data, &node); err != nil {
+			t.Fatalf("failed to unmarshal: %v", err)
+		}
+		if node.Fields["user_id"] != float64(42) {
+			t.Errorf("user_id = %v, want 42", node.Fields["user_id"])
+		}
+		if got, want := node.Fields["ch"], fmt.Sprint(ch); got != want {
+			t.Errorf("ch = %v, want %q", got, want)
+		}
+	})
+
+	t.Run("JSONFields collects fields of the whole chain", func(t *testing.T) {
+		if fields := JSONFields(errors.New("plain")); fields != nil {
+			t.Errorf("JSONFields without fields = %v, want nil", fields)
+		}
+		ch := make(chan int)
+		err := fmt.Errorf("outer: %w", With(errors.New("inner"), "user_id", 42, "ch", ch))
+		fields := JSONFields(err)
+		if len(fields) != 2 || fields["user_id"] != 42 || fields["ch"] != fmt.Sprint(ch) {
+			t.Errorf("JSONFields() = %v, want user_id=42 and ch as a string", fields)
+		}
+	})
+
+	t.Run("fmt.Errorf chain and errors.Join become children", func(t *testing.T) {
+		err1 := With(errors.New("error 1"))
+		err2 := errors.New("error 2")
+		err := fmt.Errorf("outer: %w", errors.Join(err1, err2))
+
+		node := NewJSONError(err)
+		if node.Type != "*fmt.wrapError" {
+			t.Errorf("Type = %q, want %q", node.Type, "*fmt.wrapError")
+		}
+		if len(node.Frames) != 0 {
+			t.Error("fmt.Errorf wrapper should not have frames")
+		}
+		if len(node.Children) != 1 {
+			t.Fatalf("fmt.Errorf wrapper should have 1 child, got %d", len(node.Children))
+		}
+		join := node.Children[0]
+		if join.Type != "*errors.joinError" {
+			t.Errorf("Type = %q, want %q", join.Type, "*errors.joinError")
+		}
+		if len(join.Children) != 2 {
+			t.Fatalf("errors.Join should have 2 children, got %d", len(join.Children))
+		}
+		if join.Children[0].Message != "error 1" || len(join.Children[0].Frames) == 0 {
+			t.Errorf("first branch should be error 1 with frames, got %+v", join.Children[0])
+		}
+		if join.Children[1].Message != "error 2" || len(join.Children[1].Frames) != 0 {
+			t.Errorf("second branch should be error 2 without frames, got %+v", join.Children[1])
+		}
+	})
+}
+
+func TestUnmarshalJSON(t *testing.T) {
+	t.Run("null decodes to nil", func(t *testing.T) {
+		decoded, err := UnmarshalJSON([]byte("null"))
+		if err != nil {
+			t.Fatalf("UnmarshalJSON returned error: %v", err)
+		}
+		if decoded != nil {
+			t.Errorf("UnmarshalJSON(null) = %v, want nil", decoded)
+		}
+	})
+
+	t.Run("invalid data returns error", func(t *testing.T) {
+		if _, err := UnmarshalJSON([]byte("{")); err == nil {
+			t.Error("UnmarshalJSON should fail for invalid JSON")
+		}
+	})
+
+	t.Run("round trip preserves messages and frames", func(t *testing.T) {
+		inner := With(errors.New("inner"))
+		original := fmt.Errorf("outer: %w", errors.Join(inner, errors.New("plain")))
+
+		data, err := MarshalJSON(original)
+		if err != nil {
+			t.Fatalf("MarshalJSON returned error: %v", err)
+		}
+		decoded, err := UnmarshalJSON(data)
+		if err != nil {
+			t.Fatalf("UnmarshalJSON returned error: %v", err)
+		}
+
+		if decoded.Error() != original.Error() {
+			t.Errorf("Error() = %q, want %q", decoded.Error(), original.Error())
+		}
+
+		var walked []string
+		var frames []StackFrame
+		WalkStack(decoded, func(err error, f []StackFrame) {
+			walked = append(walked, err.Error())
+			frames = f
+		})
+		if len(walked) != 1 || walked[0] != "inner" {
+			t.Fatalf("WalkStack should find the inner stack, got %v", walked)
+		}
+
+		want := inner.(*withStack).StackFrames()
+		if len(frames) != len(want) {
+			t.Fatalf("decoded %d frames, want %d", len(frames), len(want))
+		}
+		for i := range want {
+			if frames[i] != want[i] {
+				t.Errorf("frame %d = %+v, want %+v", i, frames[i], want[i])
+			}
+		}
+
+		if !strings.Contains(ErrorStack(decoded), "json_test.go") {
+			t.Error("ErrorStack of decoded error should contain stack trace")
+		}
+	})
+
+	t.Run("round trip preserves inlined frames", func(t *testing.T) {
+		want := []StackFrame{
+			{File: "/src/app/handler.go", LineNumber: 12, Name: "parse", Package: "example.com/app", ProgramCounter: 0x1234, Inlined: true},
+			{File: "/src/app/handler.go", LineNumber: 30, Name: "handle", Package: "example.com/app", ProgramCounter: 0x1234},
+		}
+		data, err := json.Marshal(&JSONError{Message: "inner", Type: "*errors.errorString", Frames: newJSONFrames(want)})
+		if err != nil {
+			t.Fatalf("json.Marshal returned error: %v", err)
+		}
+		if !strings.Contains(string(data), `"inlined":true`) {
+			t.Errorf("inlined frame should be marked in JSON, got: %s", data)
+		}
+		decoded, err := UnmarshalJSON(data)
+		if err != nil {
+			t.Fatalf("UnmarshalJSON returned error: %v", err)
+		}
+		frames := decoded.(interface{ StackFrames() []StackFrame }).StackFrames()
+		if len(frames) != len(want) {
+			t.Fatalf("decoded %d frames, want %d", len(frames), len(want))
+		}
+		for i := range want {
+			if frames[i] != want[i] {
+				t.Errorf("frame %d = %+v, want %+v", i, frames[i], want[i])
+			}
+		}
+	})
+
+	t.Run("round trip preserves hand-off labels", func(t *testing.T) {
+		original := Rethrow(fmt.Errorf("ctx: %w", With(errors.New("base"))))
+
+		node := NewJSONError(original)
+		if node.Label != "rethrown at" || len(node.Frames) == 0 {
+			t.Fatalf("rethrown stack should be a separate labeled node, got %+v", node)
+		}
+		if child := node.Children[0]; child.Type != "*fmt.wrapError" || child.Frames != nil {
+			t.Errorf("rethrown stack should not be merged into the wrapped error, got %+v", child)
+		}
+
+		data, err := MarshalJSON(original)
+		if err != nil {
+			t.Fatalf("MarshalJSON returned error: %v", err)
+		}
+		decoded, err := UnmarshalJSON(data)
+		if err != nil {
+			t.Fatalf("UnmarshalJSON returned error: %v", err)
+		}
+		if got, want := ErrorStack(decoded), ErrorStack(original); got != want {
+			t.Errorf("ErrorStack of decoded error =\n%s\nwant:\n%s", got, want)
+		}
+	})
+
+	t.Run("re-encoding a decoded error is stable", func(t *testing.T) {
+		original := fmt.Errorf("outer: %w", With(errors.New("inner")))
+
+		first, err := MarshalJSON(original)
+		if err != nil {
+			t.Fatalf("MarshalJSON returned error: %v", err)
+		}
+		decoded, err := UnmarshalJSON(first)
+		if err != nil {
+			t.Fatalf("UnmarshalJSON returned error: %v", err)
+		}
+		second, err := MarshalJSON(decoded)
+		if err != nil {
+			t.Fatalf("MarshalJSON returned error: %v", err)
+		}
+		if string(first) != string(second) {
+			t.Errorf("re-encoded JSON differs:\n%s\n%s", first, second)
+		}
+	})
+}
@@ -0,0 +1,142 @@
+package errstk
+
+import (
+	"fmt"
+	"path"
+	"runtime/debug"
+	"strings"
+	"sync"
+
+	"github.com/tomoemon/go-errstk/internal/importpath"
+)
+
+// buildModules is the module information of the running binary.
+type buildModules struct {
+	// mainPackage is the import path of package main, or empty if unknown.
+	mainPackage string
+	// main is the main module, or nil if unknown.
+	main *debug.Module
+	// deps are the dependency modules.
+	deps []*debug.Module
+}
+
+// readBuildModules returns the module information recorded by the Go toolchain,
+// or an empty buildModules if build information is not available.
+var readBuildModules = sync.OnceValue(func() buildModules {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return buildModules{}
+	}
+	mods := buildModules{deps: info.Deps}
+	if info.Main.Path != "" {
+		mods.main = &info.Main
+	}
+	// Test binaries report the package under test with a ".test" suffix,
+	// and their package main is the generated test main, which has no source.
+	if info.Path != "command-line-arguments" && !strings.HasSuffix(info.Path, ".test") {
+		mods.mainPackage = info.Path
+	}
+	return mods
+})
+
+// module returns the module that provides the package with import path pkg,
+// or nil if pkg is not in a module, e.g. in the standard library.
+func (m buildModules) module(pkg string) *debug.Module {
+	var best *debug.Module
+	if m.main != nil && inModule(pkg, m.main.Path) {
+		best = m.main
+	}
+	for _, mod := range m.deps {
+		if inModule(pkg, mod.Path) && (best == nil || len(mod.Path) > len(best.Path)) {
+			best = mod
+		}
+	}
+	return best
+}
+
+// inModule reports whether the package pkg is under the module path mod.
+func inModule(pkg, mod string) bool {
+	return pkg == mod || strings.HasPrefix(pkg, mod+"/")
+}
+
+// ModuleFile returns the path of the frame's source file relative to the module
+// cache, GOROOT or build directory, so that it is the same on every machine and
+// in -trimpath builds. It is derived from the frame's package and the module
+// information of the running binary:
+//
+//   - files of dependencies become module@version/dir/file.go,
+//     e.g. "golang.org/x/sync@v0.17.0/errgroup/errgroup.go"
+//   - files of the main module become module/dir/file.go, without a version,
+//     e.g. "example.com/app/internal/db/db.go"
+//   - files of the standard library become dir/file.go, e.g. "net/http/server.go"
+//
+// Returns File unchanged if the package cannot be attributed to a module, for
+// example when build information is not available or for package main of a
+// test binary.
+func (frame *StackFrame) ModuleFile() string {
+	return readBuildModules().moduleFile(frame)
+}
+
+// moduleFile implements StackFrame.ModuleFile for the modules in m.
+func (m buildModules) moduleFile(frame *StackFrame) string {
+	if frame.File == "" || frame.Package == "" {
+		return frame.File
+	}
+
+	pkg := frame.Package
+	if pkg == "main" {
+		if m.mainPackage == "" {
+			return frame.File
+		}
+		pkg = m.mainPackage
+	}
+	// External test packages live in the directory of the package they test.
+	pkg = strings.TrimSuffix(pkg, "_test")
+	base := path.Base(strings.ReplaceAll(frame.File, "\\", "/"))
+
+	mod := m.module(pkg)
+	switch {
+	case mod == nil:
+		// Packages of the main module were attributed above.
+		if importpath.IsStandard(pkg) {
+			return pkg + "/" + base
+		}
+		return frame.File
+	case mod == m.main || mod.Version == "" || mod.Version == "(devel)":
+		return pkg + "/" + base
+	default:
+		return fmt.Sprintf("%s@%s%s/%s", mod.Path, mod.Version, strings.TrimPrefix(pkg, mod.Path), base)
+	}
+}
+
+// TrimPathStackFrameFormatter formats frames like the default formatter, but with
+// the file path returned by StackFrame.ModuleFile, which trims GOROOT, the module
+// cache (GOPATH/pkg/mod) and the main module's root directory. The output does not
+// leak build machine paths and is the same for builds with and without -trimpath.
+// Paths that ModuleFile cannot attribute to a module are printed with everything up
+// to the module cache removed, or unchanged.
+//
+// Example:
+//
+//	func init() {
+//	    errstk.DefaultStackFrameFormatter = errstk.TrimPathStackFrameFormatter
+//	}
+//
+// Output:
+//
+//	example.com/app/internal/db.Query()
+//		example.com/app/internal/db/db.go:42 +0x1234567
+//	golang.org/x/sync/errgroup.(*Group).Go.func1()
+//		golang.org/x/sync@v0.17.0/errgroup/errgroup.go:93 +0x1234567
+func TrimPathStackFrameFormatter(frame *StackFrame) string {
+	if frame.Collapsed > 0 {
+		return formatCollapsedFrame(frame)
+	}
+	file := frame.ModuleFile()
+	if file == frame.File {
+		if _, rel, ok := strings.Cut(strings.ReplaceAll(file, "\\", "/"), "/pkg/mod/"); ok {
+			file = rel
+		}
+	}
+	return fmt.Sprintf("%s()\n\t%s:%d +0x%x\n", frame.FullName(), file, frame.LineNumber, frame.ProgramCounter)
+}
@@ -0,0 +1,137 @@
+package errstk
+
+import (
+	"errors"
+	"runtime/debug"
+	"strings"
+	"testing"
+)
+
+func TestModuleFile(t *testing.T) {
+	mods := buildModules{
+		mainPackage: "example.com/app/cmd/server",
+		main:        &debug.Module{Path: "example.com/app", Version: "(devel)"},
+		deps: []*debug.Module{
+			{Path: "golang.org/x/sync", Version: "v0.17.0"},
+			{Path: "example.com/app/tools", Version: "v1.2.0"},
+			{Path: "example.com/local", Version: ""},
+		},
+	}
+
+	tests := []struct {
+		name  string
+		frame StackFrame
+		want  string
+	}{
+		{
+			"main module package",
+			StackFrame{Package: "example.com/app/internal/db", File: "/home/runner/work/app/internal/db/db.go"},
+			"example.com/app/internal/db/db.go",
+		},
+		{
+			"main module root package",
+			StackFrame{Package: "example.com/app", File: "/home/runner/work/app/app.go"},
+			"example.com/app/app.go",
+		},
+		{
+			"package main",
+			StackFrame{Package: "main", File: "/home/runner/work/app/cmd/server/main.go"},
+			"example.com/app/cmd/server/main.go",
+		},
+		{
+			"external test package",
+			StackFrame{Package: "example.com/app/internal/db_test", File: "/home/runner/work/app/internal/db/db_test.go"},
+			"example.com/app/internal/db/db_test.go",
+		},
+		{
+			"dependency",
+			StackFrame{Package: "golang.org/x/sync/errgroup", File: "/root/go/pkg/mod/golang.org/x/sync@v0.17.0/errgroup/errgroup.go"},
+			"golang.org/x/sync@v0.17.0/errgroup/errgroup.go",
+		},
+		{
+			"nested module",
+			StackFrame{Package: "example.com/app/tools/gen", File: "/root/go/pkg/mod/example.com/app/tools@v1.2.0/gen/gen.go"},
+			"example.com/app/tools@v1.2.0/gen/gen.go",
+		},
+		{
+			"dependency without version",
+			StackFrame{Package: "example.com/local/util", File: "/src/local/util/util.go"},
+			"example.com/local/util/util.go",
+		},
+		{
+			"trimpath dependency",
+			StackFrame{Package: "golang.org/x/sync/errgroup", File: "golang.org/x/sync@v0.17.0/errgroup/errgroup.go"},
+			"golang.org/x/sync@v0.17.0/errgroup/errgroup.go",
+		},
+		{
+			"standard library",
+			StackFrame{Package: "net/http", File: "/usr/local/go/src/net/http/server.go"},
+			"net/http/server.go",
+		},
+		{
+			"trimpath standard library",
+			StackFrame{Package: "runtime", File: "runtime/proc.go"},
+			"runtime/proc.go",
+		},
+		{
+			"unknown module",
+			StackFrame{Package: "example.com/other", File: "/src/other/other.go"},
+			"/src/other/other.go",
+		},
+		{
+			"no package",
+			StackFrame{File: "/src/other/other.go"},
+			"/src/other/other.go",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := mods.moduleFile(&tt.frame); got != tt.want {
+				t.Errorf("moduleFile() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("package main of a test binary", func(t *testing.T) {
+		frame := StackFrame{Package: "main", File: "/tmp/go-build/b001/_testmain.go"}
+		if got := (buildModules{main: mods.main}).moduleFile(&frame); got != frame.File {
+			t.Errorf("moduleFile() = %q, want %q", got, frame.File)
+		}
+	})
+
+	t.Run("running binary", func(t *testing.T) {
+		frames := With(errors.New("test error")).(*withStack).StackFrames()
+		if got, want := frames[0].ModuleFile(), "github.com/tomoemon/go-errstk/module_test.go"; got != want {
+			t.Errorf("ModuleFile() = %q, want %q", got, want)
+		}
+		last := frames[len(frames)-1]
+		if got := last.ModuleFile(); !strings.HasPrefix(got, "runtime/") {
+			t.Errorf("ModuleFile() = %q, want a path under runtime/", got)
+		}
+	})
+}
+
+func TestTrimPathStackFrameFormatter(t *testing.T) {
+	frame := StackFrame{
+		Package:        "example.com/other",
+		Name:           "Do",
+		File:           "/root/go/pkg/mod/example.com/other@v1.0.0/other.go",
+		LineNumber:     7,
+		ProgramCounter: 0x10,
+	}
+	want := "example.com/other.Do()\n\texample.com/other@v1.0.0/other.go:7 +0x10\n"
+	if got := TrimPathStackFrameFormatter(&frame); got != want {
+		t.Errorf("TrimPathStackFrameFormatter() = %q, want %q", got, want)
+	}
+
+	err := With(errors.New("test error"))
+	frame = err.(*withStack).StackFrames()[0]
+	if got := TrimPathStackFrameFormatter(&frame); !strings.Contains(got, "\tgithub.com/tomoemon/go-errstk/module_test.go:") {
+		t.Errorf("TrimPathStackFrameFormatter() = %q, want a module-relative path", got)
+	}
+
+	collapsed := StackFrame{Collapsed: 2}
+	if got, want := TrimPathStackFrameFormatter(&collapsed), "... 2 standard library frames\n"; got != want {
+		t.Errorf("TrimPathStackFrameFormatter() = %q, want %q", got, want)
+	}
+}
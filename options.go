@@ -0,0 +1,100 @@
+package errstk
+
+// Option configures a single call to WithOptions or WrapWithOptions.
+// Options only affect the call they are passed to, so they are safe to use
+// concurrently, unlike changing DefaultSkipFrames or DefaultMaxStackDepth.
+type Option func(*captureOptions)
+
+// captureOptions holds the settings used to capture a stack trace.
+type captureOptions struct {
+	// skip is the number of frames to skip above the caller of the entry point.
+	skip int
+	// depth is the maximum number of frames to capture.
+	depth int
+	// force captures a new stack even if the error chain already has one.
+	force bool
+	// kind records why the stack is captured.
+	kind captureKind
+	// fields are attached to the error along with the stack.
+	fields []Field
+	// spawn is the spawn chain given by CreatedBy; it is only used if spawnFromContext is set.
+	spawn            *spawnRecord
+	spawnFromContext bool
+}
+
+// newCaptureOptions returns the package defaults with opts applied.
+func newCaptureOptions(opts []Option) captureOptions {
+	o := captureOptions{
+		skip:  DefaultSkipFrames,
+		depth: DefaultMaxStackDepth,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// Skip sets the number of stack frames to skip when capturing the stack trace,
+// overriding DefaultSkipFrames for a single call.
+// Use Skip(1) in a helper function so that the stack starts at its caller.
+// A negative n is treated as 0.
+func Skip(n int) Option {
+	return func(o *captureOptions) {
+		o.skip = max(n, 0)
+	}
+}
+
+// Depth sets the maximum number of stack frames to capture,
+// overriding DefaultMaxStackDepth for a single call.
+// A zero or negative n is ignored, so DefaultMaxStackDepth stays in effect;
+// an empty stack would hide the error from every later capture.
+func Depth(n int) Option {
+	return func(o *captureOptions) {
+		if n > 0 {
+			o.depth = n
+		}
+	}
+}
+
+// ForceNewStack captures a new stack trace even if the error chain already has one.
+// The new stack wraps the existing error, so WalkStack and ErrorStack report both.
+func ForceNewStack() Option {
+	return func(o *captureOptions) {
+		o.force = true
+	}
+}
+
+// WithOptions is like With, but applies opts to this call only.
+//
+// Example - helper function:
+//
+//	func wrapQueryError(err error, query string) error {
+//	    // Skip wrapQueryError itself so the stack starts at its caller.
+//	    return errstk.WithOptions(fmt.Errorf("query %q: %w", query, err), errstk.Skip(1))
+//	}
+//
+//go:noinline
+func WithOptions(err error, opts ...Option) error {
+	// Skip 4 frames: WithOptions -> innerWithStack -> callers -> runtime.Callers
+	const innerSkip = 4
+	return innerWithStack(err, innerSkip, newCaptureOptions(opts))
+}
+
+// WrapWithOptions is like Wrap, but applies opts to this call only.
+// Designed for use with defer and named return values.
+//
+// Example:
+//
+//	func processData() (err error) {
+//	    defer errstk.WrapWithOptions(&err, errstk.Depth(64))
+//	    return errors.New("validation failed")
+//	}
+//
+//go:noinline
+func WrapWithOptions(errp *error, opts ...Option) {
+	if *errp != nil {
+		// Skip 4 frames: WrapWithOptions -> innerWithStack -> callers -> runtime.Callers
+		const innerSkip = 4
+		*errp = innerWithStack(*errp, innerSkip, newCaptureOptions(opts))
+	}
+}
@@ -0,0 +1,174 @@
+package errstk
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+)
+
+//go:noinline
+func helperWithOptions(err error, opts ...Option) error {
+	return WithOptions(err, opts...)
+}
+
+func TestWithOptions(t *testing.T) {
+	t.Run("nil error returns nil", func(t *testing.T) {
+		if result := WithOptions(nil, Skip(1)); result != nil {
+			t.Errorf("WithOptions(nil) = %v, want nil", result)
+		}
+	})
+
+	t.Run("without options behaves like With", func(t *testing.T) {
+		err := WithOptions(errors.New("test error"))
+
+		frames := err.(*withStack).StackFrames()
+		if !strings.HasPrefix(frames[0].Name, "TestWithOptions") {
+			t.Errorf("first frame = %q, want TestWithOptions", frames[0].Name)
+		}
+	})
+
+	t.Run("Skip skips helper frames", func(t *testing.T) {
+		err := helperWithOptions(errors.New("test error"), Skip(1))
+
+		frames := err.(*withStack).StackFrames()
+		if !strings.HasPrefix(frames[0].Name, "TestWithOptions") {
+			t.Errorf("first frame = %q, want the caller of the helper", frames[0].Name)
+		}
+		if DefaultSkipFrames != 0 {
+			t.Errorf("DefaultSkipFrames = %d, should not be modified", DefaultSkipFrames)
+		}
+	})
+
+	t.Run("Depth limits captured frames", func(t *testing.T) {
+		err := WithOptions(errors.New("test error"), Depth(2))
+
+		if n := len(err.(*withStack).Callers()); n != 2 {
+			t.Errorf("captured %d frames, want 2", n)
+		}
+	})
+
+	t.Run("zero and negative Depth are ignored", func(t *testing.T) {
+		for _, n := range []int{0, -1} {
+			err := WithOptions(errors.New("test error"), Depth(2), Depth(n))
+
+			if got := len(err.(*withStack).Callers()); got != 2 {
+				t.Errorf("Depth(%d): captured %d frames, want 2 from the earlier Depth", n, got)
+			}
+			err = WithOptions(errors.New("test error"), Depth(n))
+			frames := err.(*withStack).StackFrames()
+			if len(frames) == 0 || !strings.HasPrefix(frames[0].Name, "TestWithOptions") {
+				t.Errorf("Depth(%d): frames = %v, want the stack of the caller", n, frames)
+			}
+		}
+	})
+
+	t.Run("negative Skip is treated as 0", func(t *testing.T) {
+		err := WithOptions(errors.New("test error"), Skip(-3))
+
+		frames := err.(*withStack).StackFrames()
+		if len(frames) == 0 || !strings.HasPrefix(frames[0].Name, "TestWithOptions") {
+			t.Errorf("frames = %v, want the stack of the caller", frames)
+		}
+	})
+
+	t.Run("ForceNewStack wraps an error that already has a stack", func(t *testing.T) {
+		first := With(errors.New("test error"))
+		second := WithOptions(fmt.Errorf("context: %w", first), ForceNewStack())
+
+		if _, ok := second.(*withStack); !ok {
+			t.Fatalf("WithOptions should return *withStack, got %T", second)
+		}
+		count := 0
+		WalkStack(second, func(error, []StackFrame) {
+			count++
+		})
+		if count != 2 {
+			t.Errorf("WalkStack found %d stacks, want 2", count)
+		}
+	})
+
+	t.Run("does not double wrap without ForceNewStack", func(t *testing.T) {
+		first := With(errors.New("test error"))
+		if second := WithOptions(first, Depth(64)); second != first {
+			t.Error("WithOptions should not double wrap an error that already has a stack")
+		}
+	})
+
+	t.Run("options are concurrency-safe", func(t *testing.T) {
+		var wg sync.WaitGroup
+		for i := range 16 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				depth := i%2 + 1
+				err := WithOptions(errors.New("test error"), Depth(depth))
+				if n := len(err.(*withStack).Callers()); n != depth {
+					t.Errorf("captured %d frames, want %d", n, depth)
+				}
+			}()
+		}
+		wg.Wait()
+	})
+}
+
+func TestWrapWithOptions(t *testing.T) {
+	t.Run("nil error stays nil", func(t *testing.T) {
+		f := func() (err error) {
+			defer WrapWithOptions(&err, Depth(1))
+			return nil
+		}
+		if err := f(); err != nil {
+			t.Errorf("WrapWithOptions(nil) = %v, want nil", err)
+		}
+	})
+
+	t.Run("captures stack at the return point", func(t *testing.T) {
+		f := func() (err error) {
+			defer WrapWithOptions(&err)
+			return errors.New("test error")
+		}
+
+		frames := f().(*withStack).StackFrames()
+		if !strings.HasPrefix(frames[0].Name, "TestWrapWithOptions") {
+			t.Errorf("first frame = %q, want the deferring function", frames[0].Name)
+		}
+	})
+
+	t.Run("applies Skip and Depth", func(t *testing.T) {
+		inner := func() (err error) {
+			defer WrapWithOptions(&err, Skip(1), Depth(1))
+			return errors.New("test error")
+		}
+		outer := func() error {
+			return inner()
+		}
+
+		err := outer()
+		callers := err.(*withStack).Callers()
+		if len(callers) != 1 {
+			t.Fatalf("captured %d frames, want 1", len(callers))
+		}
+		frames := err.(*withStack).StackFrames()
+		if !strings.Contains(frames[0].Name, "func3.2") {
+			t.Errorf("first frame = %q, want the caller of the deferring function", frames[0].Name)
+		}
+	})
+
+	t.Run("ForceNewStack wraps an error that already has a stack", func(t *testing.T) {
+		original := With(errors.New("test error"))
+		f := func() (err error) {
+			defer WrapWithOptions(&err, ForceNewStack())
+			return original
+		}
+
+		err := f()
+		if err == original {
+			t.Fatal("WrapWithOptions should wrap the error again with ForceNewStack")
+		}
+		if errors.Unwrap(err) != original {
+			t.Error("the new stack should wrap the original error")
+		}
+	})
+}
@@ -0,0 +1,149 @@
+package errstk
+
+import (
+	"fmt"
+	"log/slog"
+	"slices"
+	"sync"
+)
+
+// PanicError is the error stored by Recover when a panic is recovered.
+// It keeps the original panic value and the stack of the goroutine at the point
+// where panic was called, so WalkStack and ErrorStack report the panic site
+// rather than the deferred function that recovered it.
+type PanicError struct {
+	// Value is the value passed to panic.
+	Value any
+
+	stack []uintptr
+
+	// frames memoizes the resolved stack frames; see stackFrames.
+	framesOnce sync.Once
+	frames     []StackFrame
+}
+
+// Recover converts a panic into a *PanicError stored in *errp.
+// It must be called directly by defer, and errp must not be nil.
+// The stack trace is captured from the panicking goroutine, starting at the
+// function that called panic (or caused a runtime error).
+//
+// Recover does nothing when the function returns normally. runtime.Goexit is not
+// a panic, so it is not intercepted and the goroutine still exits.
+// A panic with http.ErrAbortHandler is re-panicked, so that net/http still aborts
+// the response as the handler intended.
+//
+// When used together with Wrap, defer Recover after Wrap so that it runs first;
+// Wrap then leaves the *PanicError unchanged because it already has a stack trace.
+//
+// Example:
+//
+//	func handle() (err error) {
+//	    defer errstk.Wrap(&err)
+//	    defer errstk.Recover(&err)
+//	    ...
+//	}
+//
+//go:noinline
+func Recover(errp *error) {
+	r := recover()
+	if r == nil {
+		return
+	}
+	if isAbortHandler(r) {
+		panic(r)
+	}
+	// Skip 3 frames: Recover -> callers -> runtime.Callers
+	const innerSkip = 3
+	*errp = &PanicError{
+		Value: r,
+		stack: panicStack(callers(innerSkip, DefaultMaxStackDepth+panicStackExtraDepth)),
+	}
+}
+
+// abortHandlerMessage is the message of http.ErrAbortHandler.
+// It is matched by message so that the package does not link net/http.
+const abortHandlerMessage = "net/http: abort Handler"
+
+// isAbortHandler reports whether the panic value r is http.ErrAbortHandler.
+func isAbortHandler(r any) bool {
+	err, ok := r.(error)
+	return ok && err.Error() == abortHandlerMessage
+}
+
+// panicStackExtraDepth is the number of additional frames captured by Recover
+// to make room for the runtime frames that are trimmed by panicStack.
+const panicStackExtraDepth = 8
+
+// panicStack trims the frames of the runtime panic machinery from the top of stack,
+// so that it starts at the function that panicked.
+// If a deferred function panicked again while an earlier panic was unwinding,
+// the stack holds a gopanic frame for each; the innermost one belongs to the
+// panic being recovered.
+func panicStack(stack []uintptr) []uintptr {
+	start := 0
+	for i, pc := range stack {
+		frame := frameCache.lookup(pc)
+		if frame.Package == "runtime" && frame.Name == "gopanic" {
+			start = i + 1
+			break
+		}
+	}
+	if start > 0 {
+		// Runtime errors are raised through helpers such as runtime.panicmem
+		// and runtime.sigpanic; skip them as well.
+		for start < len(stack) && frameCache.lookup(stack[start]).Package == "runtime" {
+			start++
+		}
+	}
+	stack = stack[start:]
+	if len(stack) > DefaultMaxStackDepth {
+		stack = stack[:DefaultMaxStackDepth]
+	}
+	return stack
+}
+
+// Error returns the panic value formatted as "panic: <value>".
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v", e.Value)
+}
+
+// Unwrap returns the panic value if it is an error, so that errors.Is and
+// errors.As can inspect it. Otherwise it returns nil.
+func (e *PanicError) Unwrap() error {
+	err, _ := e.Value.(error)
+	return err
+}
+
+func (e *PanicError) Format(s fmt.State, verb rune) {
+	formatError(s, verb, e)
+}
+
+// StackFrames returns the stack frames of the panicking goroutine.
+func (e *PanicError) StackFrames() []StackFrame {
+	return slices.Clone(e.stackFrames())
+}
+
+// stackFrames returns the resolved stack frames, resolving them on first use.
+func (e *PanicError) stackFrames() []StackFrame {
+	e.framesOnce.Do(func() {
+		e.frames = stackFramesFromPC(e.stack)
+	})
+	return e.frames
+}
+
+// Callers returns the raw program counters of the panicking goroutine.
+// It satisfies the StackTracer interface.
+func (e *PanicError) Callers() []uintptr {
+	return e.stack
+}
+
+// LogValue satisfies the slog.LogValuer interface.
+// See withStack.LogValue for the format.
+func (e *PanicError) LogValue() slog.Value {
+	return logValue(e)
+}
+
+// MarshalJSON satisfies the json.Marshaler interface.
+func (e *PanicError) MarshalJSON() ([]byte, error) {
+	return MarshalJSON(e)
+}
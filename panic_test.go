@@ -0,0 +1,195 @@
+package errstk
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+var errPanicValue = errors.New("panic value")
+
+//go:noinline
+func panicWith(v any) (err error) {
+	defer Wrap(&err)
+	defer Recover(&err)
+	panicker(v)
+	return nil
+}
+
+//go:noinline
+func panicker(v any) {
+	panic(v)
+}
+
+//go:noinline
+func panicInDefer() (err error) {
+	defer Recover(&err)
+	defer func() {
+		panic("second")
+	}()
+	panicker("first")
+	return nil
+}
+
+//go:noinline
+func nilDereference() (err error) {
+	defer Recover(&err)
+	var p *int
+	_ = *p
+	return nil
+}
+
+func TestRecover(t *testing.T) {
+	t.Run("no panic leaves err unchanged", func(t *testing.T) {
+		f := func() (err error) {
+			defer Recover(&err)
+			return errPanicValue
+		}
+		if err := f(); err != errPanicValue {
+			t.Errorf("Recover should not change err, got %v", err)
+		}
+	})
+
+	t.Run("panic value is kept", func(t *testing.T) {
+		err := panicWith("boom")
+
+		var panicErr *PanicError
+		if !errors.As(err, &panicErr) {
+			t.Fatalf("err should be *PanicError, got %T", err)
+		}
+		if panicErr.Value != "boom" {
+			t.Errorf("Value = %v, want boom", panicErr.Value)
+		}
+		if err.Error() != "panic: boom" {
+			t.Errorf("Error() = %q, want %q", err.Error(), "panic: boom")
+		}
+	})
+
+	t.Run("error panic value is unwrapped", func(t *testing.T) {
+		err := panicWith(fmt.Errorf("wrapped: %w", errPanicValue))
+		if !errors.Is(err, errPanicValue) {
+			t.Error("errors.Is should find the panic value")
+		}
+	})
+
+	t.Run("stack starts at the panic site", func(t *testing.T) {
+		err := panicWith("boom")
+
+		frames := err.(*PanicError).StackFrames()
+		if len(frames) == 0 {
+			t.Fatal("StackFrames should not be empty")
+		}
+		if frames[0].Name != "panicker" {
+			t.Errorf("first frame = %s, want panicker", frames[0].FullName())
+		}
+		if len(frames) < 2 || frames[1].Name != "panicWith" {
+			t.Errorf("second frame should be panicWith, got %v", frameNames(frames))
+		}
+		for _, frame := range frames {
+			if frame.Name == "Recover" || frame.Name == "gopanic" {
+				t.Errorf("stack should not contain %s, got %v", frame.FullName(), frameNames(frames))
+			}
+		}
+		if len(err.(*PanicError).Callers()) != len(frames) {
+			t.Error("Callers and StackFrames should have the same length")
+		}
+	})
+
+	t.Run("runtime error frames are trimmed", func(t *testing.T) {
+		err := nilDereference()
+
+		var runtimeErr runtime.Error
+		if !errors.As(err, &runtimeErr) {
+			t.Fatalf("err should wrap runtime.Error, got %v", err)
+		}
+		frames := err.(*PanicError).StackFrames()
+		if frames[0].Name != "nilDereference" {
+			t.Errorf("first frame = %s, want nilDereference", frames[0].FullName())
+		}
+	})
+
+	t.Run("nested panic reports the last panic site", func(t *testing.T) {
+		err := panicInDefer()
+
+		var panicErr *PanicError
+		if !errors.As(err, &panicErr) {
+			t.Fatalf("err should be *PanicError, got %T", err)
+		}
+		if panicErr.Value != "second" {
+			t.Errorf("Value = %v, want second", panicErr.Value)
+		}
+		frames := panicErr.StackFrames()
+		if len(frames) == 0 || frames[0].Name != "panicInDefer.func1" {
+			t.Errorf("stack should start at the deferred function that panicked, got %v", frames)
+		}
+	})
+
+	t.Run("Wrap does not add a second stack", func(t *testing.T) {
+		err := panicWith("boom")
+		if _, ok := err.(*PanicError); !ok {
+			t.Errorf("Wrap should leave *PanicError unchanged, got %T", err)
+		}
+
+		count := 0
+		WalkStack(fmt.Errorf("outer: %w", err), func(error, []StackFrame) {
+			count++
+		})
+		if count != 1 {
+			t.Errorf("WalkStack found %d stacks, want 1", count)
+		}
+	})
+
+	t.Run("ErrorStack and %+v show the panic site", func(t *testing.T) {
+		err := panicWith("boom")
+
+		for name, output := range map[string]string{
+			"ErrorStack": ErrorStack(fmt.Errorf("handler: %w", err)),
+			"%+v":        fmt.Sprintf("%+v", err),
+		} {
+			if !strings.Contains(output, "panic: boom\n") || !strings.Contains(output, "errstk.panicker()") {
+				t.Errorf("%s should contain the panic message and site, got:\n%s", name, output)
+			}
+		}
+	})
+
+	t.Run("http.ErrAbortHandler is re-panicked", func(t *testing.T) {
+		var err error
+		defer func() {
+			if r := recover(); r != http.ErrAbortHandler {
+				t.Errorf("recovered %v, want http.ErrAbortHandler", r)
+			}
+			if err != nil {
+				t.Errorf("ErrAbortHandler should not be converted to an error, got %v", err)
+			}
+		}()
+		func() {
+			defer Recover(&err)
+			panic(http.ErrAbortHandler)
+		}()
+	})
+
+	t.Run("abortHandlerMessage matches http.ErrAbortHandler", func(t *testing.T) {
+		if got := http.ErrAbortHandler.Error(); got != abortHandlerMessage {
+			t.Errorf("http.ErrAbortHandler.Error() = %q, want %q", got, abortHandlerMessage)
+		}
+	})
+
+	t.Run("runtime.Goexit is not intercepted", func(t *testing.T) {
+		done := make(chan error)
+		go func() {
+			var err error
+			defer func() { done <- err }()
+			func() {
+				defer Recover(&err)
+				runtime.Goexit()
+			}()
+			err = errors.New("unreachable")
+		}()
+		if err := <-done; err != nil {
+			t.Errorf("Goexit should not be converted to an error, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,244 @@
+package errstk
+
+import (
+	"strconv"
+	"strings"
+)
+
+// ParsedStack is one section of a stack trace read back from text by ParseStacks.
+type ParsedStack struct {
+	// Message is the text above the frames: an error message (possibly spanning
+	// several lines for errors.Join), or a goroutine header such as
+	// "goroutine 1 [running]:" in runtime dumps. It is empty for labeled sections.
+	Message string
+
+	// Label is set for the sections headed by a label instead of a message:
+	// "rethrown at", "spawned at" or "created by". A "created by <func> in goroutine N"
+	// line of a runtime dump is reported as a "created by" section with a single frame.
+	Label string
+
+	// Fields are the fields listed on a "fields: key=value ..." line.
+	// Values are strings, and a value containing spaces cannot be told apart
+	// from separate fields.
+	Fields []Field
+
+	// Frames are the parsed frames, or nil for a section with only a message,
+	// such as the full message that ErrorStack prints above the stacks of a wrapped
+	// error. ProgramCounter holds the hex value printed after the location;
+	// runtime dumps print an offset from the function entry there instead.
+	// Lines elided by DefaultElideCommonFrames are restored from the previous section,
+	// and collapsed standard library frames are returned as frames with only Collapsed set.
+	Frames []StackFrame
+}
+
+// ParseStacks parses stack traces in the text format written by ErrorStack, %+v and
+// the default DefaultStackFrameFormatter, including errors.Join output, as well as
+// runtime/debug.Stack output and the goroutine dumps printed for unrecovered panics.
+// Sections are returned in the order they appear. Lines that are not part of a
+// frame start a new message; blank lines, the source context lines printed when
+// DefaultSourceContextLines is set, and runtime notes such as
+// "...additional frames elided..." are skipped.
+//
+// Example:
+//
+//	for _, s := range errstk.ParseStacks(logLine) {
+//	    fmt.Println(s.Message, len(s.Frames))
+//	}
+func ParseStacks(text string) []ParsedStack {
+	lines := strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+
+	var stacks []ParsedStack
+	// message and fields are the header of the next section, not yet added to stacks.
+	var message []string
+	var fields []Field
+	// inFrames reports whether the last section in stacks is still accepting frames.
+	inFrames := false
+
+	flush := func() {
+		if len(message) > 0 || fields != nil {
+			stacks = append(stacks, ParsedStack{Message: strings.Join(message, "\n"), Fields: fields})
+			message, fields = nil, nil
+		}
+	}
+
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		if line == "" || line == "...additional frames elided..." {
+			flush()
+			inFrames = false
+			continue
+		}
+		if isSourceContextLine(line) {
+			continue
+		}
+		if rest, ok := strings.CutPrefix(line, "fields: "); ok && len(message) > 0 {
+			fields = parseFields(rest)
+			continue
+		}
+		if isLabelLine(line) {
+			flush()
+			stacks = append(stacks, ParsedStack{Label: strings.TrimSuffix(line, ":")})
+			inFrames = true
+			continue
+		}
+
+		if inFrames {
+			last := &stacks[len(stacks)-1]
+			if n, ok := parseCountLine(line, " frames in common with above", " frame in common with above"); ok {
+				last.Frames = append(last.Frames, commonFrames(stacks[:len(stacks)-1], n)...)
+				continue
+			}
+			if n, ok := parseCountLine(line, " standard library frames", " standard library frame"); ok {
+				last.Frames = append(last.Frames, StackFrame{Collapsed: n})
+				continue
+			}
+		}
+
+		if i+1 < len(lines) && strings.HasPrefix(lines[i+1], "\t") {
+			if name, ok := parseCreatedBy(line); ok {
+				flush()
+				stacks = append(stacks, ParsedStack{
+					Label:  kindCreated.label(),
+					Frames: []StackFrame{parseFrame(name, lines[i+1])},
+				})
+				inFrames = false
+				i++
+				continue
+			}
+			if name, ok := parseFuncLine(line); ok {
+				if len(message) > 0 || fields != nil || !inFrames {
+					stacks = append(stacks, ParsedStack{Message: strings.Join(message, "\n"), Fields: fields})
+					message, fields = nil, nil
+					inFrames = true
+				}
+				last := &stacks[len(stacks)-1]
+				last.Frames = append(last.Frames, parseFrame(name, lines[i+1]))
+				i++
+				continue
+			}
+		}
+
+		inFrames = false
+		message = append(message, line)
+	}
+	flush()
+	return stacks
+}
+
+// commonFrames returns the last n frames of the last section in stacks that has frames.
+func commonFrames(stacks []ParsedStack, n int) []StackFrame {
+	for i := len(stacks) - 1; i >= 0; i-- {
+		if frames := stacks[i].Frames; frames != nil {
+			if n > len(frames) {
+				return nil
+			}
+			return frames[len(frames)-n:]
+		}
+	}
+	return nil
+}
+
+// isLabelLine reports whether line is the heading of a hand-off stack in ErrorStack.
+func isLabelLine(line string) bool {
+	label, ok := strings.CutSuffix(line, ":")
+	if !ok {
+		return false
+	}
+	for kind := kindRethrown; kind <= kindCreated; kind++ {
+		if kind.label() == label {
+			return true
+		}
+	}
+	return false
+}
+
+// isSourceContextLine reports whether line is a source line printed below a frame
+// by formatSourceContext, such as "\t>  42 |\treturn err".
+func isSourceContextLine(line string) bool {
+	rest, ok := strings.CutPrefix(line, "\t")
+	if !ok || len(rest) < 2 || (rest[0] != ' ' && rest[0] != '>') || rest[1] != ' ' {
+		return false
+	}
+	number, _, ok := strings.Cut(strings.TrimLeft(rest[2:], " "), " |")
+	if !ok {
+		return false
+	}
+	_, err := strconv.Atoi(number)
+	return err == nil
+}
+
+// parseCountLine parses a summary line such as "... 3 standard library frames",
+// given its plural and singular suffixes.
+func parseCountLine(line, plural, singular string) (int, bool) {
+	rest, ok := strings.CutPrefix(line, "... ")
+	if !ok {
+		return 0, false
+	}
+	count, ok := strings.CutSuffix(rest, plural)
+	if !ok {
+		if count, ok = strings.CutSuffix(rest, singular); !ok {
+			return 0, false
+		}
+	}
+	n, err := strconv.Atoi(count)
+	if err != nil {
+		return 0, false
+	}
+	return n, true
+}
+
+// parseCreatedBy parses the "created by main.main in goroutine 1" line of a runtime dump.
+func parseCreatedBy(line string) (string, bool) {
+	name, ok := strings.CutPrefix(line, "created by ")
+	if !ok {
+		return "", false
+	}
+	name, _, _ = strings.Cut(name, " in goroutine ")
+	return name, true
+}
+
+// parseFuncLine parses a function line such as "main.(*T).f(0x1, ...)"
+// and returns the fully qualified function name.
+func parseFuncLine(line string) (string, bool) {
+	if !strings.HasSuffix(line, ")") {
+		return "", false
+	}
+	i := strings.LastIndex(line, "(")
+	if i <= 0 {
+		return "", false
+	}
+	return line[:i], true
+}
+
+// parseFrame builds a frame from a function name and its location line,
+// such as "\t/path/to/file.go:123 +0x1a".
+func parseFrame(name, location string) StackFrame {
+	var frame StackFrame
+	frame.Package, frame.Name = packageAndName(name)
+
+	location = strings.TrimPrefix(location, "\t")
+	// Runtime dumps may append " fp=0x... sp=0x... pc=0x..." after the offset.
+	location, offset, _ := strings.Cut(location, " +0x")
+	offset, _, _ = strings.Cut(offset, " ")
+	if i := strings.LastIndex(location, ":"); i >= 0 {
+		frame.File = location[:i]
+		frame.LineNumber, _ = strconv.Atoi(location[i+1:])
+	} else {
+		frame.File = location
+	}
+	if offset != "" {
+		pc, _ := strconv.ParseUint(offset, 16, 64)
+		frame.ProgramCounter = uintptr(pc)
+	}
+	return frame
+}
+
+// parseFields parses the key=value pairs of a "fields:" line.
+func parseFields(s string) []Field {
+	var fields []Field
+	for _, pair := range strings.Fields(s) {
+		key, value, _ := strings.Cut(pair, "=")
+		fields = append(fields, Field{Key: key, Value: value})
+	}
+	return fields
+}
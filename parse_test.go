@@ -0,0 +1,186 @@
+package errstk
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"runtime/debug"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// printedFrames returns the frames of every stack in err as they appear in ErrorStack,
+// without the fields that the text format does not include.
+func printedFrames(err error) [][]StackFrame {
+	var stacks [][]StackFrame
+	WalkStack(err, func(_ error, frames []StackFrame) {
+		frames = slices.Clone(frames)
+		for i := range frames {
+			if frames[i].Collapsed > 0 {
+				frames[i] = StackFrame{Collapsed: frames[i].Collapsed}
+			}
+			frames[i].Inlined = false
+		}
+		stacks = append(stacks, frames)
+	})
+	return stacks
+}
+
+func TestParseStacks(t *testing.T) {
+	t.Run("single stack round trips", func(t *testing.T) {
+		err := With(errors.New("test error"))
+
+		stacks := ParseStacks(ErrorStack(err))
+		if len(stacks) != 1 {
+			t.Fatalf("ParseStacks returned %d sections, want 1: %+v", len(stacks), stacks)
+		}
+		if stacks[0].Message != "test error" {
+			t.Errorf("Message = %q, want %q", stacks[0].Message, "test error")
+		}
+		if want := printedFrames(err)[0]; !reflect.DeepEqual(stacks[0].Frames, want) {
+			t.Errorf("Frames = %v\nwant %v", stacks[0].Frames, want)
+		}
+		if !reflect.DeepEqual(ParseStacks(fmt.Sprintf("%+v", err)), stacks) {
+			t.Errorf("%%+v output should parse the same as ErrorStack")
+		}
+	})
+
+	t.Run("wrapped errors.Join round trips", func(t *testing.T) {
+		err := fmt.Errorf("outer: %w", joinTwoStacks())
+
+		stacks := ParseStacks(ErrorStack(err))
+		if len(stacks) != 3 {
+			t.Fatalf("ParseStacks returned %d sections, want 3: %+v", len(stacks), stacks)
+		}
+		if stacks[0].Message != "outer: error 1\nerror 2" || stacks[0].Frames != nil {
+			t.Errorf("first section should be the full message, got %+v", stacks[0])
+		}
+		want := printedFrames(err)
+		for i, message := range []string{"error 1", "error 2"} {
+			if stacks[i+1].Message != message {
+				t.Errorf("Message = %q, want %q", stacks[i+1].Message, message)
+			}
+			if !reflect.DeepEqual(stacks[i+1].Frames, want[i]) {
+				t.Errorf("Frames of %q = %v\nwant %v", message, stacks[i+1].Frames, want[i])
+			}
+		}
+	})
+
+	t.Run("elided common frames are restored", func(t *testing.T) {
+		setElideCommonFrames(t, true)
+		err := joinTwoStacks()
+
+		stackTrace := ErrorStack(err)
+		if !strings.Contains(stackTrace, "in common with above") {
+			t.Fatalf("ErrorStack should elide frames, got:\n%s", stackTrace)
+		}
+		stacks := ParseStacks(stackTrace)
+		want := printedFrames(err)
+		if len(stacks) != 3 || !reflect.DeepEqual(stacks[2].Frames, want[1]) {
+			t.Errorf("ParseStacks should restore the elided frames, got %+v\nwant %v", stacks, want[1])
+		}
+	})
+
+	t.Run("collapsed frames", func(t *testing.T) {
+		setFrameFilter(t, &FrameFilter{CollapseStdlib: true})
+		err := With(errors.New("test error"))
+
+		stacks := ParseStacks(ErrorStack(err))
+		if want := printedFrames(err)[0]; len(stacks) != 1 || !reflect.DeepEqual(stacks[0].Frames, want) {
+			t.Errorf("Frames = %v\nwant %v", stacks, want)
+		}
+	})
+
+	t.Run("source context lines are skipped", func(t *testing.T) {
+		setSourceContextLines(t, 2)
+		err := fmt.Errorf("outer: %w", With(errors.New("test error")))
+
+		stackTrace := ErrorStack(err)
+		if !strings.Contains(stackTrace, " |") {
+			t.Fatalf("ErrorStack should include source context, got:\n%s", stackTrace)
+		}
+		stacks := ParseStacks(stackTrace)
+		if len(stacks) != 2 {
+			t.Fatalf("ParseStacks returned %d sections, want 2: %+v", len(stacks), stacks)
+		}
+		if stacks[0].Message != "outer: test error" || stacks[1].Message != "test error" {
+			t.Errorf("messages = %q, %q, want the full and origin messages", stacks[0].Message, stacks[1].Message)
+		}
+		if want := printedFrames(err)[0]; !reflect.DeepEqual(stacks[1].Frames, want) {
+			t.Errorf("Frames = %v\nwant %v", stacks[1].Frames, want)
+		}
+	})
+
+	t.Run("fields and hand-off labels", func(t *testing.T) {
+		err := Rethrow(fmt.Errorf("lookup: %w", With(errors.New("not found"), "user_id", 42)))
+
+		stacks := ParseStacks(ErrorStack(err))
+		if len(stacks) != 3 {
+			t.Fatalf("ParseStacks returned %d sections, want 3: %+v", len(stacks), stacks)
+		}
+		if stacks[0].Message != "lookup: not found" || fmt.Sprint(stacks[0].Fields) != "[user_id=42]" {
+			t.Errorf("first section = %+v, want full message and fields", stacks[0])
+		}
+		if stacks[1].Message != "not found" || len(stacks[1].Frames) == 0 {
+			t.Errorf("second section should be the origin stack, got %+v", stacks[1])
+		}
+		if stacks[2].Label != "rethrown at" || stacks[2].Message != "" || len(stacks[2].Frames) == 0 {
+			t.Errorf("third section should be the rethrown-at stack, got %+v", stacks[2])
+		}
+	})
+
+	t.Run("runtime/debug.Stack", func(t *testing.T) {
+		stacks := ParseStacks(string(debug.Stack()))
+		if len(stacks) != 2 {
+			t.Fatalf("ParseStacks returned %d sections, want 2: %+v", len(stacks), stacks)
+		}
+		if !strings.HasPrefix(stacks[0].Message, "goroutine ") {
+			t.Errorf("Message = %q, want goroutine header", stacks[0].Message)
+		}
+		if frame := stacks[0].Frames[0]; frame.FullName() != "runtime/debug.Stack" {
+			t.Errorf("first frame = %s, want runtime/debug.Stack", frame.FullName())
+		}
+		if frame := stacks[0].Frames[1]; !strings.HasPrefix(frame.Name, "TestParseStacks.func") || !strings.HasSuffix(frame.File, "parse_test.go") {
+			t.Errorf("second frame = %+v, want this test", frame)
+		}
+		if stacks[1].Label != "created by" || stacks[1].Frames[0].FullName() != "testing.(*T).Run" {
+			t.Errorf("second section should be created by testing.(*T).Run, got %+v", stacks[1])
+		}
+	})
+
+	t.Run("panic goroutine dump", func(t *testing.T) {
+		dump := "panic: boom [recovered]\n" +
+			"\n" +
+			"goroutine 7 [running]:\n" +
+			"main.(*worker).run(0xc000010000, {0x4b2f60, 0x3})\n" +
+			"\t/src/app/main.go:21 +0x3c fp=0xc00004af80 sp=0xc00004af50 pc=0x4ac3fc\n" +
+			"main.helper[...](...)\n" +
+			"\t/src/app/main.go:15\n" +
+			"created by main.main in goroutine 1\n" +
+			"\t/src/app/main.go:9 +0x25\n" +
+			"\n" +
+			"goroutine 1 [chan receive]:\n" +
+			"main.main()\n" +
+			"\t/src/app/main.go:10 +0x31\n" +
+			"exit status 2\n"
+
+		want := []ParsedStack{
+			{Message: "panic: boom [recovered]"},
+			{Message: "goroutine 7 [running]:", Frames: []StackFrame{
+				{Package: "main", Name: "(*worker).run", File: "/src/app/main.go", LineNumber: 21, ProgramCounter: 0x3c},
+				{Package: "main", Name: "helper[...]", File: "/src/app/main.go", LineNumber: 15},
+			}},
+			{Label: "created by", Frames: []StackFrame{
+				{Package: "main", Name: "main", File: "/src/app/main.go", LineNumber: 9, ProgramCounter: 0x25},
+			}},
+			{Message: "goroutine 1 [chan receive]:", Frames: []StackFrame{
+				{Package: "main", Name: "main", File: "/src/app/main.go", LineNumber: 10, ProgramCounter: 0x31},
+			}},
+			{Message: "exit status 2"},
+		}
+		if got := ParseStacks(dump); !reflect.DeepEqual(got, want) {
+			t.Errorf("ParseStacks() =\n%+v\nwant\n%+v", got, want)
+		}
+	})
+}
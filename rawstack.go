@@ -0,0 +1,91 @@
+package errstk
+
+import (
+	"os"
+	"runtime"
+	"sync"
+
+	"github.com/tomoemon/go-errstk/internal/buildid"
+)
+
+// RawStacks is the unresolved form of the stacks of an error chain: the program
+// counters returned by runtime.Callers, without file names, line numbers or function
+// names. It is cheap to build and to serialize, and can be resolved offline with the
+// errstk-symbolize command and an unstripped copy of the binary:
+//
+//	go run github.com/tomoemon/go-errstk/cmd/errstk-symbolize -binary ./app payload.json
+//
+// Position-independent binaries are loaded at a different address on every run.
+// The load base is recorded as AnchorPC, the address at which the function AnchorFunc
+// was loaded; the symbolizer compares it with the address of the same function in
+// the binary to translate the program counters.
+type RawStacks struct {
+	// BuildID is the Go build ID of the running binary, as printed by
+	// "go tool buildid", or empty if it could not be read.
+	BuildID string `json:"build_id"`
+	// AnchorFunc is the name of the function whose address is AnchorPC.
+	AnchorFunc string `json:"anchor_func"`
+	// AnchorPC is the entry address of AnchorFunc in the running process.
+	AnchorPC uintptr `json:"anchor_pc"`
+	// Stacks are the stacks of the chain, in the same order as WalkStack reports them.
+	Stacks []RawStack `json:"stacks"`
+}
+
+// RawStack is one unresolved stack of an error chain.
+type RawStack struct {
+	// Message is the Error() text of the error carrying the stack.
+	Message string `json:"message"`
+	// Label is the label of a hand-off stack, such as "rethrown at", or empty.
+	Label string `json:"label,omitempty"`
+	// PCs are the program counters of the stack, as returned by runtime.Callers.
+	// Frames removed by DefaultFrameFilter are included.
+	PCs []uintptr `json:"pcs"`
+}
+
+// NewRawStacks returns the unresolved stacks of err and its chain.
+// Only stacks found by DefaultStackExtractors are included.
+// Returns nil if err is nil.
+func NewRawStacks(err error) *RawStacks {
+	if err == nil {
+		return nil
+	}
+	name, pc := rawStackAnchor()
+	raw := &RawStacks{
+		BuildID:    executableBuildID(),
+		AnchorFunc: name,
+		AnchorPC:   pc,
+		Stacks:     []RawStack{},
+	}
+	walkChain(err, func(e chainError) bool {
+		if e.hasStack {
+			raw.Stacks = append(raw.Stacks, RawStack{Message: e.err.Error(), Label: e.label, PCs: e.stack})
+		}
+		return true
+	})
+	return raw
+}
+
+// rawStackAnchor returns the name and entry address of itself,
+// which serve as the anchor of RawStacks.
+//
+//go:noinline
+func rawStackAnchor() (string, uintptr) {
+	pc, _, _, _ := runtime.Caller(0)
+	fn := runtime.FuncForPC(pc)
+	return fn.Name(), fn.Entry()
+}
+
+// executableBuildID returns the Go build ID of the running binary,
+// or an empty string if it cannot be read.
+var executableBuildID = sync.OnceValue(func() string {
+	path, err := os.Executable()
+	if err != nil {
+		return ""
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+	return buildid.Read(f)
+})
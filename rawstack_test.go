@@ -0,0 +1,55 @@
+package errstk
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"slices"
+	"testing"
+)
+
+func TestNewRawStacks(t *testing.T) {
+	t.Run("nil error", func(t *testing.T) {
+		if raw := NewRawStacks(nil); raw != nil {
+			t.Errorf("NewRawStacks(nil) = %v, want nil", raw)
+		}
+	})
+
+	t.Run("error without stack", func(t *testing.T) {
+		raw := NewRawStacks(errors.New("plain"))
+		if len(raw.Stacks) != 0 {
+			t.Errorf("Stacks = %v, want none", raw.Stacks)
+		}
+	})
+
+	t.Run("anchor", func(t *testing.T) {
+		raw := NewRawStacks(errors.New("plain"))
+		fn := runtime.FuncForPC(raw.AnchorPC)
+		if fn == nil || fn.Name() != raw.AnchorFunc || fn.Entry() != raw.AnchorPC {
+			t.Errorf("anchor %s at %#x does not resolve to itself", raw.AnchorFunc, raw.AnchorPC)
+		}
+		if runtime.GOOS == "linux" && raw.BuildID == "" {
+			t.Error("BuildID should be read from the test binary")
+		}
+	})
+
+	t.Run("stacks in WalkStack order", func(t *testing.T) {
+		origin := With(errors.New("origin"))
+		rethrown := Rethrow(fmt.Errorf("handed off: %w", origin))
+		other := With(errors.New("other"))
+		err := errors.Join(rethrown, other)
+
+		raw := NewRawStacks(err)
+		var got []string
+		for _, s := range raw.Stacks {
+			got = append(got, s.Message+"|"+s.Label)
+		}
+		want := []string{"origin|", "handed off: origin|rethrown at", "other|"}
+		if !slices.Equal(got, want) {
+			t.Errorf("stacks = %v, want %v", got, want)
+		}
+		if !slices.Equal(raw.Stacks[0].PCs, origin.(*withStack).Callers()) {
+			t.Error("PCs should be the program counters of the stack")
+		}
+	})
+}
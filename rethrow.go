@@ -0,0 +1,80 @@
+package errstk
+
+// captureKind describes why a stack trace was captured.
+type captureKind int
+
+const (
+	// kindOrigin is a stack captured where the error was created or first wrapped.
+	kindOrigin captureKind = iota
+	// kindRethrown is a stack captured where an error was handed off by Rethrow.
+	kindRethrown
+	// kindSpawned is a stack captured where Group.Go started the goroutine
+	// that returned the error.
+	kindSpawned
+	// kindCreated is the stack of the goroutine that started the current one with Go.
+	kindCreated
+)
+
+// label returns the heading used for stacks of this kind in ErrorStack,
+// or an empty string for origin stacks, which are headed by the error message.
+func (k captureKind) label() string {
+	switch k {
+	case kindRethrown:
+		return "rethrown at"
+	case kindSpawned:
+		return "spawned at"
+	case kindCreated:
+		return "created by"
+	}
+	return ""
+}
+
+// StackLabel returns the label of the stack carried by err itself: "rethrown at",
+// "spawned at" or "created by" for stacks attached by Rethrow, Group and Go, or an
+// empty string for a stack captured where the error was created or first wrapped.
+// Use it in a WalkStack callback to tell capture points apart.
+func StackLabel(err error) string {
+	return captureLabel(err)
+}
+
+// captureLabel returns the label of the stack carried by err itself, if any.
+func captureLabel(err error) string {
+	switch e := err.(type) {
+	case *withStack:
+		return e.kind.label()
+	case *decodedError:
+		return e.label
+	}
+	return ""
+}
+
+// Rethrow attaches an additional "rethrown at" stack trace to err at the point
+// Rethrow was called, even if err already carries a stack trace.
+// Use it where an error crosses a goroutine or layer boundary, e.g. when it is
+// received from a channel or returned by errgroup.Group.Wait, so that both the
+// place where the error started and the place where it was handed off are recorded.
+//
+// WalkStack and ErrorStack report the stacks in capture order: the original stack
+// first, then each "rethrown at" stack.
+//
+// Returns nil if err is nil.
+// If err has no stack trace yet, Rethrow behaves like With.
+//
+// Example:
+//
+//	select {
+//	case err := <-errCh:
+//	    return errstk.Rethrow(err)
+//	}
+//
+//go:noinline
+func Rethrow(err error) error {
+	opts := newCaptureOptions(nil)
+	if HasStack(err) {
+		opts.force = true
+		opts.kind = kindRethrown
+	}
+	// Skip 4 frames: Rethrow -> innerWithStack -> callers -> runtime.Callers
+	const innerSkip = 4
+	return innerWithStack(err, innerSkip, opts)
+}
@@ -0,0 +1,129 @@
+package errstk
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+//go:noinline
+func failInGoroutine(errCh chan<- error) {
+	errCh <- With(errors.New("worker failed"))
+}
+
+//go:noinline
+func receiveAndRethrow(errCh <-chan error) error {
+	return Rethrow(<-errCh)
+}
+
+func TestRethrow(t *testing.T) {
+	t.Run("nil error returns nil", func(t *testing.T) {
+		if result := Rethrow(nil); result != nil {
+			t.Errorf("Rethrow(nil) = %v, want nil", result)
+		}
+	})
+
+	t.Run("StackLabel tells the stacks apart", func(t *testing.T) {
+		var labels []string
+		WalkStack(Rethrow(With(errors.New("test error"))), func(err error, _ []StackFrame) {
+			labels = append(labels, StackLabel(err))
+		})
+		if len(labels) != 2 || labels[0] != "" || labels[1] != "rethrown at" {
+			t.Errorf("labels = %q, want [\"\" \"rethrown at\"]", labels)
+		}
+	})
+
+	t.Run("error without stack behaves like With", func(t *testing.T) {
+		originalErr := errors.New("test error")
+		err := Rethrow(originalErr)
+
+		w, ok := err.(*withStack)
+		if !ok {
+			t.Fatalf("Rethrow should return *withStack, got %T", err)
+		}
+		if w.kind != kindOrigin {
+			t.Error("Rethrow of an error without stack should capture an origin stack")
+		}
+		if !strings.HasPrefix(ErrorStack(err), "test error\n") {
+			t.Errorf("ErrorStack should start with the error message, got:\n%s", ErrorStack(err))
+		}
+	})
+
+	t.Run("attaches a second stack across a goroutine boundary", func(t *testing.T) {
+		errCh := make(chan error, 1)
+		go failInGoroutine(errCh)
+		err := receiveAndRethrow(errCh)
+
+		if err.Error() != "worker failed" {
+			t.Errorf("Error() = %q, want %q", err.Error(), "worker failed")
+		}
+
+		var names []string
+		WalkStack(err, func(_ error, frames []StackFrame) {
+			names = append(names, frames[0].Name)
+		})
+		want := []string{"failInGoroutine", "receiveAndRethrow"}
+		if strings.Join(names, ",") != strings.Join(want, ",") {
+			t.Errorf("WalkStack visited %v, want %v", names, want)
+		}
+	})
+
+	t.Run("ErrorStack reports capture points in order", func(t *testing.T) {
+		errCh := make(chan error, 1)
+		go failInGoroutine(errCh)
+		err := fmt.Errorf("handler: %w", receiveAndRethrow(errCh))
+
+		stackTrace := ErrorStack(err)
+		origin := strings.Index(stackTrace, "worker failed\n")
+		rethrown := strings.Index(stackTrace, "rethrown at:\n")
+		if origin < 0 || rethrown < 0 {
+			t.Fatalf("ErrorStack should contain both stacks, got:\n%s", stackTrace)
+		}
+		if origin > rethrown {
+			t.Errorf("original stack should come before the rethrown stack, got:\n%s", stackTrace)
+		}
+		if !strings.HasPrefix(stackTrace, "handler: worker failed\n") {
+			t.Errorf("ErrorStack should start with the full message, got:\n%s", stackTrace)
+		}
+	})
+
+	t.Run("ErrorStack shows the full message of a rethrown wrapped error", func(t *testing.T) {
+		err := Rethrow(fmt.Errorf("ctx: %w", With(errors.New("base"))))
+
+		stackTrace := ErrorStack(err)
+		if !strings.HasPrefix(stackTrace, "ctx: base\n\nbase\n") {
+			t.Errorf("ErrorStack should start with the full message, got:\n%s", stackTrace)
+		}
+		if !strings.Contains(stackTrace, "rethrown at:\n") {
+			t.Errorf("ErrorStack should contain the rethrown stack, got:\n%s", stackTrace)
+		}
+	})
+
+	t.Run("%+v shows every stack", func(t *testing.T) {
+		errCh := make(chan error, 1)
+		go failInGoroutine(errCh)
+		err := receiveAndRethrow(errCh)
+
+		formatted := fmt.Sprintf("%+v", err)
+		if !strings.Contains(formatted, "failInGoroutine") || !strings.Contains(formatted, "receiveAndRethrow") {
+			t.Errorf("%%+v should contain both stacks, got:\n%s", formatted)
+		}
+	})
+
+	t.Run("preserves error chain", func(t *testing.T) {
+		baseErr := errors.New("base error")
+		err := Rethrow(Rethrow(With(baseErr)))
+
+		if !errors.Is(err, baseErr) {
+			t.Error("Rethrow should preserve the error chain")
+		}
+		count := 0
+		WalkStack(err, func(error, []StackFrame) {
+			count++
+		})
+		if count != 3 {
+			t.Errorf("WalkStack found %d stacks, want 3", count)
+		}
+	})
+}
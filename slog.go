@@ -0,0 +1,194 @@
+package errstk
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+)
+
+// LogValue satisfies the slog.LogValuer interface.
+// The error is logged as a group containing the error message ("msg"),
+// the fields collected by Fields, and a "stack" group with one attribute
+// per captured frame.
+//
+// Example output with slog.JSONHandler:
+//
+//	{"err":{"msg":"file not found","user_id":42,"stack":{"0":"main.load /path/to/main.go:42","1":"main.main /path/to/main.go:12"}}}
+func (w *withStack) LogValue() slog.Value {
+	return logValue(w)
+}
+
+// logValue implements slog.LogValuer for the error types of this package,
+// with the default StackHandlerOptions.
+func logValue(err error) slog.Value {
+	value, _ := errorLogValue(err, defaultStackHandlerOptions)
+	return value
+}
+
+// StackHandlerOptions configures a StackHandler.
+type StackHandlerOptions struct {
+	// MaxDepth limits the number of frames emitted for each stack.
+	// Zero or a negative value emits every captured frame.
+	MaxDepth int
+
+	// FormatFrame converts a stack frame into the value of its attribute.
+	// If nil, frames are formatted as "package.Function /path/to/file.go:123".
+	FormatFrame func(frame StackFrame) slog.Value
+}
+
+// defaultStackHandlerOptions are the options used by LogValue.
+var defaultStackHandlerOptions = StackHandlerOptions{
+	FormatFrame: defaultLogFrameFormatter,
+}
+
+// StackHandler is an slog.Handler middleware that expands error attributes
+// into structured stack traces before passing the record to the next handler.
+//
+// Every attribute whose value is an error is inspected with WalkStack.
+// If a stack trace is found, the attribute is replaced with a group containing
+// the error message ("msg"), the fields collected by Fields, and the frames:
+//   - a single stack is emitted as a "stack" group
+//   - multiple stacks (e.g. from errors.Join) are emitted as a "stacks" group
+//     with one {"msg", "stack"} group per stack
+//
+// Errors without a stack trace are passed through unchanged.
+type StackHandler struct {
+	next slog.Handler
+	opts StackHandlerOptions
+}
+
+// NewStackHandler returns a StackHandler that writes to next.
+// If opts is nil, the default options are used.
+//
+// Example:
+//
+//	logger := slog.New(errstk.NewStackHandler(slog.NewJSONHandler(os.Stderr, nil), &errstk.StackHandlerOptions{
+//	    MaxDepth: 10,
+//	}))
+//	logger.Error("request failed", "err", err)
+func NewStackHandler(next slog.Handler, opts *StackHandlerOptions) *StackHandler {
+	h := &StackHandler{next: next}
+	if opts != nil {
+		h.opts = *opts
+	}
+	if h.opts.FormatFrame == nil {
+		h.opts.FormatFrame = defaultLogFrameFormatter
+	}
+	return h
+}
+
+// Enabled reports whether the next handler handles records at the given level.
+func (h *StackHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+// Handle expands error attributes of r and passes the result to the next handler.
+func (h *StackHandler) Handle(ctx context.Context, r slog.Record) error {
+	expanded := slog.NewRecord(r.Time, r.Level, r.Message, r.PC)
+	r.Attrs(func(a slog.Attr) bool {
+		expanded.AddAttrs(h.expandAttr(a))
+		return true
+	})
+	return h.next.Handle(ctx, expanded)
+}
+
+// WithAttrs returns a new StackHandler whose next handler has the expanded attrs.
+func (h *StackHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	expanded := make([]slog.Attr, len(attrs))
+	for i, a := range attrs {
+		expanded[i] = h.expandAttr(a)
+	}
+	return &StackHandler{next: h.next.WithAttrs(expanded), opts: h.opts}
+}
+
+// WithGroup returns a new StackHandler whose next handler has the given group.
+func (h *StackHandler) WithGroup(name string) slog.Handler {
+	return &StackHandler{next: h.next.WithGroup(name), opts: h.opts}
+}
+
+func (h *StackHandler) expandAttr(a slog.Attr) slog.Attr {
+	switch a.Value.Kind() {
+	case slog.KindGroup:
+		group := a.Value.Group()
+		expanded := make([]slog.Attr, len(group))
+		for i, ga := range group {
+			expanded[i] = h.expandAttr(ga)
+		}
+		return slog.Attr{Key: a.Key, Value: slog.GroupValue(expanded...)}
+	case slog.KindAny, slog.KindLogValuer:
+		err, ok := a.Value.Any().(error)
+		if !ok {
+			return a
+		}
+		if value, ok := errorLogValue(err, h.opts); ok {
+			return slog.Attr{Key: a.Key, Value: value}
+		}
+	}
+	return a
+}
+
+// errorLogValue returns the structured value of err used by LogValue and StackHandler.
+// It reports false if no stack trace is found in the error chain.
+func errorLogValue(err error, opts StackHandlerOptions) (slog.Value, bool) {
+	type stack struct {
+		err    error
+		frames []StackFrame
+	}
+	var stacks []stack
+	WalkStack(err, func(err error, frames []StackFrame) {
+		stacks = append(stacks, stack{err, frames})
+	})
+	if len(stacks) == 0 {
+		return slog.Value{}, false
+	}
+
+	attrs := []slog.Attr{slog.String("msg", err.Error())}
+	for _, f := range Fields(err) {
+		attrs = append(attrs, slog.Any(f.Key, f.Value))
+	}
+	if len(stacks) == 1 {
+		if label := captureLabel(stacks[0].err); label != "" {
+			attrs = append(attrs, slog.String("label", label))
+		}
+		attrs = append(attrs, slog.Attr{Key: "stack", Value: stackLogValue(stacks[0].frames, opts.MaxDepth, opts.FormatFrame)})
+		return slog.GroupValue(attrs...), true
+	}
+
+	stackAttrs := make([]slog.Attr, len(stacks))
+	for i, s := range stacks {
+		group := []any{slog.String("msg", s.err.Error())}
+		// Hand-off stacks carry the label of their capture point,
+		// e.g. "rethrown at", so that they are not mistaken for origin stacks.
+		if label := captureLabel(s.err); label != "" {
+			group = append(group, slog.String("label", label))
+		}
+		group = append(group, slog.Attr{Key: "stack", Value: stackLogValue(s.frames, opts.MaxDepth, opts.FormatFrame)})
+		stackAttrs[i] = slog.Group(strconv.Itoa(i), group...)
+	}
+	attrs = append(attrs, slog.Attr{Key: "stacks", Value: slog.GroupValue(stackAttrs...)})
+	return slog.GroupValue(attrs...), true
+}
+
+// stackLogValue returns a group value with one attribute per frame,
+// keyed by the frame index.
+func stackLogValue(frames []StackFrame, maxDepth int, format func(StackFrame) slog.Value) slog.Value {
+	if maxDepth > 0 && len(frames) > maxDepth {
+		frames = frames[:maxDepth]
+	}
+	attrs := make([]slog.Attr, len(frames))
+	for i, frame := range frames {
+		attrs[i] = slog.Attr{Key: strconv.Itoa(i), Value: format(frame)}
+	}
+	return slog.GroupValue(attrs...)
+}
+
+// defaultLogFrameFormatter formats a frame as "package.Function /path/to/file.go:123".
+// Summary frames created by FrameFilter.CollapseStdlib are formatted as "... 3 standard library frames".
+func defaultLogFrameFormatter(frame StackFrame) slog.Value {
+	if frame.Collapsed > 0 {
+		return slog.StringValue(strings.TrimSuffix(formatCollapsedFrame(&frame), "\n"))
+	}
+	return slog.StringValue(fmt.Sprintf("%s %s:%d", frame.FullName(), frame.File, frame.LineNumber))
+}
@@ -0,0 +1,164 @@
+package errstk
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+// logJSON logs a single record through h and decodes the JSON output.
+func logJSON(t *testing.T, wrap func(slog.Handler) slog.Handler, args ...any) map[string]any {
+	t.Helper()
+	var buf bytes.Buffer
+	var h slog.Handler = slog.NewJSONHandler(&buf, nil)
+	if wrap != nil {
+		h = wrap(h)
+	}
+	slog.New(h).Error("failed", args...)
+
+	var record map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatalf("failed to decode log output %q: %v", buf.String(), err)
+	}
+	return record
+}
+
+func TestLogValue(t *testing.T) {
+	t.Run("logs message and stack group", func(t *testing.T) {
+		err := With(errors.New("test error"))
+
+		record := logJSON(t, nil, "err", err)
+
+		group, ok := record["err"].(map[string]any)
+		if !ok {
+			t.Fatalf("err attribute should be a group, got %v", record["err"])
+		}
+		if group["msg"] != "test error" {
+			t.Errorf("msg = %v, want %q", group["msg"], "test error")
+		}
+		stack, ok := group["stack"].(map[string]any)
+		if !ok {
+			t.Fatalf("stack attribute should be a group, got %v", group["stack"])
+		}
+		first, _ := stack["0"].(string)
+		if !strings.Contains(first, "TestLogValue") || !strings.Contains(first, "slog_test.go") {
+			t.Errorf("first frame should point to the test, got %q", first)
+		}
+		if len(stack) != len(err.(*withStack).StackFrames()) {
+			t.Errorf("stack group has %d frames, want %d", len(stack), len(err.(*withStack).StackFrames()))
+		}
+	})
+}
+
+func TestStackHandler(t *testing.T) {
+	withHandler := func(opts *StackHandlerOptions) func(slog.Handler) slog.Handler {
+		return func(next slog.Handler) slog.Handler {
+			return NewStackHandler(next, opts)
+		}
+	}
+
+	t.Run("expands error wrapped with fmt.Errorf", func(t *testing.T) {
+		err := fmt.Errorf("outer: %w", With(errors.New("inner")))
+
+		record := logJSON(t, withHandler(nil), "err", err)
+
+		group, ok := record["err"].(map[string]any)
+		if !ok {
+			t.Fatalf("err attribute should be a group, got %v", record["err"])
+		}
+		if group["msg"] != "outer: inner" {
+			t.Errorf("msg = %v, want %q", group["msg"], "outer: inner")
+		}
+		if _, ok := group["stack"].(map[string]any); !ok {
+			t.Errorf("stack attribute should be a group, got %v", group["stack"])
+		}
+	})
+
+	t.Run("errors.Join emits one group per stack", func(t *testing.T) {
+		err := errors.Join(With(errors.New("error 1")), With(errors.New("error 2")))
+
+		record := logJSON(t, withHandler(nil), "err", err)
+
+		group := record["err"].(map[string]any)
+		stacks, ok := group["stacks"].(map[string]any)
+		if !ok {
+			t.Fatalf("stacks attribute should be a group, got %v", group["stacks"])
+		}
+		if len(stacks) != 2 {
+			t.Fatalf("stacks should contain 2 groups, got %d", len(stacks))
+		}
+		second := stacks["1"].(map[string]any)
+		if second["msg"] != "error 2" {
+			t.Errorf("second msg = %v, want %q", second["msg"], "error 2")
+		}
+	})
+
+	t.Run("rethrown stacks carry their label", func(t *testing.T) {
+		err := Rethrow(fmt.Errorf("ctx: %w", With(errors.New("base"))))
+
+		record := logJSON(t, withHandler(nil), "err", err)
+
+		stacks := record["err"].(map[string]any)["stacks"].(map[string]any)
+		if len(stacks) != 2 {
+			t.Fatalf("stacks should contain 2 groups, got %d", len(stacks))
+		}
+		if origin := stacks["0"].(map[string]any); origin["label"] != nil {
+			t.Errorf("origin stack should have no label, got %v", origin["label"])
+		}
+		if rethrown := stacks["1"].(map[string]any); rethrown["label"] != "rethrown at" {
+			t.Errorf("label = %v, want %q", rethrown["label"], "rethrown at")
+		}
+	})
+
+	t.Run("respects MaxDepth and FormatFrame", func(t *testing.T) {
+		err := With(errors.New("test error"))
+		opts := &StackHandlerOptions{
+			MaxDepth: 1,
+			FormatFrame: func(frame StackFrame) slog.Value {
+				return slog.IntValue(frame.LineNumber)
+			},
+		}
+
+		record := logJSON(t, withHandler(opts), "err", err)
+
+		stack := record["err"].(map[string]any)["stack"].(map[string]any)
+		if len(stack) != 1 {
+			t.Fatalf("stack should contain 1 frame, got %d", len(stack))
+		}
+		want := float64(err.(*withStack).StackFrames()[0].LineNumber)
+		if stack["0"] != want {
+			t.Errorf("frame = %v, want %v", stack["0"], want)
+		}
+	})
+
+	t.Run("expands errors in WithAttrs and nested groups", func(t *testing.T) {
+		err := With(errors.New("test error"))
+
+		record := logJSON(t, func(next slog.Handler) slog.Handler {
+			return withHandler(nil)(next).WithAttrs([]slog.Attr{slog.Any("cause", err)})
+		}, slog.Group("req", slog.Any("err", err)))
+
+		if _, ok := record["cause"].(map[string]any)["stack"]; !ok {
+			t.Errorf("cause attribute should contain stack, got %v", record["cause"])
+		}
+		req := record["req"].(map[string]any)
+		if _, ok := req["err"].(map[string]any)["stack"]; !ok {
+			t.Errorf("nested err attribute should contain stack, got %v", req["err"])
+		}
+	})
+
+	t.Run("errors without stack are unchanged", func(t *testing.T) {
+		record := logJSON(t, withHandler(nil), "err", errors.New("plain"), "count", 1)
+
+		if record["err"] != "plain" {
+			t.Errorf("err = %v, want %q", record["err"], "plain")
+		}
+		if record["count"] != float64(1) {
+			t.Errorf("count = %v, want 1", record["count"])
+		}
+	})
+}
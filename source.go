@@ -0,0 +1,186 @@
+package errstk
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+// DefaultSourceContextLines is the number of source lines that ErrorStack and %+v
+// print before and after the line of each in-app frame, with the line of the frame
+// marked by ">". In-app frames are those of package main and of the packages in the
+// main module, as reported by runtime/debug.ReadBuildInfo.
+// Frames whose source file cannot be read are printed without context.
+// By default it is 0 and no source is printed.
+//
+// Example output with DefaultSourceContextLines = 1:
+//
+//	main.load()
+//		/path/to/main.go:12 +0x1234567
+//		   11 |	id := 42
+//		>  12 |	return errstk.With(errors.New("not found"))
+//		   13 | }
+//
+// Note: This setting is global and should be set at package initialization time only
+// to avoid race conditions.
+var DefaultSourceContextLines = 0
+
+// DefaultSourceCacheSize is the maximum number of source files kept in memory
+// by SourceLine, SourceContext and source context output.
+// Set it to 0 to disable the cache.
+// Advanced users can set this at package initialization time if needed.
+var DefaultSourceCacheSize = 64
+
+// SourceContextLine is one line of source code returned by SourceContext.
+type SourceContextLine struct {
+	// Number is the 1-based line number in the file.
+	Number int
+	// Text is the line without its trailing newline.
+	Text string
+	// Current reports whether this is the line of the frame.
+	Current bool
+}
+
+// SourceContext returns the line of the frame together with up to before lines
+// above it and up to after lines below it. Fewer lines are returned near the
+// start or end of the file.
+// Returns an error if the source file cannot be read, and nil if the frame has no
+// line number or the line is beyond the end of the file.
+func (frame *StackFrame) SourceContext(before, after int) ([]SourceContextLine, error) {
+	if frame.LineNumber <= 0 {
+		return nil, nil
+	}
+	lines, err := frame.sourceLines()
+	if err != nil {
+		return nil, err
+	}
+	i := frame.LineNumber - 1
+	if i >= len(lines) {
+		return nil, nil
+	}
+
+	start, end := max(0, i-max(0, before)), min(len(lines), i+1+max(0, after))
+	context := make([]SourceContextLine, 0, end-start)
+	for n := start; n < end; n++ {
+		context = append(context, SourceContextLine{Number: n + 1, Text: lines[n], Current: n == i})
+	}
+	return context, nil
+}
+
+// formatSourceContext returns the source context lines printed below frame,
+// or an empty string if none are configured or available.
+func formatSourceContext(frame *StackFrame) string {
+	n := DefaultSourceContextLines
+	if n <= 0 || frame.Collapsed > 0 || !isInApp(frame) {
+		return ""
+	}
+	context, err := frame.SourceContext(n, n)
+	if err != nil || len(context) == 0 {
+		return ""
+	}
+
+	width := len(fmt.Sprint(context[len(context)-1].Number))
+	var b strings.Builder
+	for _, line := range context {
+		marker := " "
+		if line.Current {
+			marker = ">"
+		}
+		fmt.Fprintf(&b, "\t%s %*d |%s\n", marker, width+1, line.Number, line.Text)
+	}
+	return b.String()
+}
+
+// mainModulePath returns the path of the main module, or an empty string
+// if build information is not available.
+func mainModulePath() string {
+	if mod := readBuildModules().main; mod != nil {
+		return mod.Path
+	}
+	return ""
+}
+
+// isInApp reports whether frame belongs to package main or to the main module.
+func isInApp(frame *StackFrame) bool {
+	if frame.Package == "main" {
+		return true
+	}
+	mod := mainModulePath()
+	return mod != "" && inModule(frame.Package, mod)
+}
+
+// sourceFiles is the process-wide cache of source file contents.
+var sourceFiles sourceFileCache
+
+// sourceFile is the cached result of reading a source file.
+type sourceFile struct {
+	lines []string
+	err   error
+}
+
+// sourceFileCache memoizes the lines of source files, including failures to read them.
+// It is safe for concurrent use and holds at most DefaultSourceCacheSize files;
+// when full, an arbitrary file is evicted to make room for a new one.
+type sourceFileCache struct {
+	mu    sync.RWMutex
+	files map[string]sourceFile
+}
+
+// lines returns the lines of the file at path, reading and caching it if needed.
+func (c *sourceFileCache) lines(path string) ([]string, error) {
+	limit := DefaultSourceCacheSize
+	if limit <= 0 {
+		return readSourceFile(path)
+	}
+
+	c.mu.RLock()
+	file, ok := c.files[path]
+	c.mu.RUnlock()
+	if ok {
+		return file.lines, file.err
+	}
+
+	file.lines, file.err = readSourceFile(path)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.files == nil {
+		c.files = make(map[string]sourceFile)
+	}
+	if len(c.files) >= limit {
+		for p := range c.files {
+			delete(c.files, p)
+			break
+		}
+	}
+	c.files[path] = file
+	return file.lines, file.err
+}
+
+// reset empties the cache.
+func (c *sourceFileCache) reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.files = nil
+}
+
+// readSourceFile returns the lines of the file at path.
+func readSourceFile(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var lines []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return lines, nil
+}
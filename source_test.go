@@ -0,0 +1,122 @@
+package errstk
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// setSourceContextLines changes DefaultSourceContextLines for the duration of a test.
+func setSourceContextLines(t *testing.T, n int) {
+	t.Helper()
+	saved := DefaultSourceContextLines
+	DefaultSourceContextLines = n
+	t.Cleanup(func() {
+		DefaultSourceContextLines = saved
+	})
+}
+
+func TestSourceContext(t *testing.T) {
+	t.Run("lines around the frame", func(t *testing.T) {
+		err := With(errors.New("source context")) // the line of the frame
+		frame := err.(*withStack).StackFrames()[0]
+
+		context, cErr := frame.SourceContext(1, 2)
+		if cErr != nil {
+			t.Fatalf("SourceContext returned error: %v", cErr)
+		}
+		if len(context) != 4 {
+			t.Fatalf("SourceContext returned %d lines, want 4: %v", len(context), context)
+		}
+		if context[0].Number != frame.LineNumber-1 || context[3].Number != frame.LineNumber+2 {
+			t.Errorf("line numbers = %d..%d, want %d..%d", context[0].Number, context[3].Number, frame.LineNumber-1, frame.LineNumber+2)
+		}
+		if !context[1].Current || !strings.Contains(context[1].Text, "// the line of the frame") {
+			t.Errorf("second line should be the current line, got %+v", context[1])
+		}
+		if context[0].Current || context[2].Current {
+			t.Error("only the line of the frame should be current")
+		}
+	})
+
+	t.Run("clamped to the file", func(t *testing.T) {
+		frame := StackFrame{File: "source_test.go", LineNumber: 1}
+		context, err := frame.SourceContext(5, 0)
+		if err != nil {
+			t.Fatalf("SourceContext returned error: %v", err)
+		}
+		if len(context) != 1 || context[0].Text != "package errstk" {
+			t.Errorf("SourceContext() = %v, want only the first line", context)
+		}
+
+		frame.LineNumber = 100000
+		if context, err := frame.SourceContext(1, 1); err != nil || context != nil {
+			t.Errorf("SourceContext beyond the end = %v, %v, want nil, nil", context, err)
+		}
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		frame := StackFrame{File: "/nonexistent/file.go", LineNumber: 10}
+		if _, err := frame.SourceContext(1, 1); err == nil {
+			t.Error("SourceContext should return an error for a missing file")
+		}
+		if _, err := frame.SourceLine(); err == nil {
+			t.Error("SourceLine should return an error for a missing file")
+		}
+	})
+
+	t.Run("source files are cached", func(t *testing.T) {
+		sourceFiles.reset()
+		frame := StackFrame{File: "source_test.go", LineNumber: 1}
+		for range 3 {
+			if line, err := frame.SourceLine(); err != nil || line != "package errstk" {
+				t.Fatalf("SourceLine() = %q, %v, want package errstk", line, err)
+			}
+		}
+		if n := len(sourceFiles.files); n != 1 {
+			t.Errorf("cache holds %d files, want 1", n)
+		}
+	})
+}
+
+func TestDefaultSourceContextLines(t *testing.T) {
+	t.Run("disabled by default", func(t *testing.T) {
+		err := With(errors.New("test error"))
+		if strings.Contains(ErrorStack(err), " |") {
+			t.Errorf("ErrorStack should not contain source by default, got:\n%s", ErrorStack(err))
+		}
+	})
+
+	t.Run("ErrorStack and %+v show source of in-app frames", func(t *testing.T) {
+		setSourceContextLines(t, 1)
+		err := With(errors.New("test error")) // marked line
+
+		for name, output := range map[string]string{
+			"ErrorStack": ErrorStack(fmt.Errorf("outer: %w", err)),
+			"%+v":        fmt.Sprintf("%+v", err),
+		} {
+			lines := strings.Split(output, "\n")
+			var marked []string
+			for _, line := range lines {
+				if strings.HasPrefix(line, "\t>") {
+					marked = append(marked, line)
+				}
+			}
+			if len(marked) != 1 || !strings.HasSuffix(marked[0], "// marked line") {
+				t.Errorf("%s should mark only the line of the in-app frame, got:\n%s", name, output)
+			}
+			if strings.Count(output, " |") != 3 {
+				t.Errorf("%s should print 3 source lines, got:\n%s", name, output)
+			}
+		}
+	})
+
+	t.Run("missing sources are skipped", func(t *testing.T) {
+		setSourceContextLines(t, 2)
+		frame := StackFrame{File: "/nonexistent/file.go", LineNumber: 10, Package: "main", Name: "main"}
+		if got := formatSourceContext(&frame); got != "" {
+			t.Errorf("formatSourceContext() = %q, want empty string", got)
+		}
+	})
+}
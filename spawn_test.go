@@ -0,0 +1,275 @@
+package errstk
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// spawnAndWrap calls With in a goroutine started by Go and returns the result.
+//
+//go:noinline
+func spawnAndWrap() error {
+	errCh := make(chan error, 1)
+	Go(func() {
+		errCh <- With(errors.New("worker failed"))
+	})
+	return <-errCh
+}
+
+// spawnNested starts a goroutine with Go that starts another one with Go.
+//
+//go:noinline
+func spawnNested() error {
+	errCh := make(chan error, 1)
+	Go(func() {
+		Go(func() {
+			errCh <- With(errors.New("worker failed"))
+		})
+	})
+	return <-errCh
+}
+
+func createdByStacks(err error) [][]StackFrame {
+	var stacks [][]StackFrame
+	WalkStack(err, func(err error, frames []StackFrame) {
+		if captureLabel(err) == "created by" {
+			stacks = append(stacks, frames)
+		}
+	})
+	return stacks
+}
+
+func TestGo(t *testing.T) {
+	t.Run("errors outside Go have no created-by stack", func(t *testing.T) {
+		err := With(errors.New("test error"))
+		if stacks := createdByStacks(err); len(stacks) != 0 {
+			t.Errorf("found %d created-by stacks, want 0", len(stacks))
+		}
+		if _, ok := err.(*withStack); !ok || err.(*withStack).kind != kindOrigin {
+			t.Errorf("With should return an origin stack, got %T", err)
+		}
+	})
+
+	t.Run("With inside Go links the parent stack", func(t *testing.T) {
+		err := spawnAndWrap()
+
+		stacks := createdByStacks(err)
+		if len(stacks) != 1 {
+			t.Fatalf("found %d created-by stacks, want 1", len(stacks))
+		}
+		if stacks[0][0].Name != "spawnAndWrap" {
+			t.Errorf("created-by stack should start at the caller of Go, got %s", frameNames(stacks[0]))
+		}
+
+		stackTrace := ErrorStack(err)
+		if !strings.HasPrefix(stackTrace, "worker failed\n") {
+			t.Errorf("ErrorStack should start with the worker stack, got:\n%s", stackTrace)
+		}
+		if !strings.Contains(stackTrace, "\n\ncreated by:\ngithub.com/tomoemon/go-errstk.spawnAndWrap()\n") {
+			t.Errorf("ErrorStack should contain the created-by stack, got:\n%s", stackTrace)
+		}
+		if err.Error() != "worker failed" {
+			t.Errorf("created-by link should not change the message, got %q", err.Error())
+		}
+	})
+
+	t.Run("nested goroutines form a chain", func(t *testing.T) {
+		err := spawnNested()
+
+		stacks := createdByStacks(err)
+		if len(stacks) != 2 {
+			t.Fatalf("found %d created-by stacks, want 2", len(stacks))
+		}
+		if !strings.HasPrefix(stacks[0][0].Name, "spawnNested.func1") {
+			t.Errorf("first created-by stack should be the nearest parent, got %s", frameNames(stacks[0]))
+		}
+		if stacks[1][0].Name != "spawnNested" {
+			t.Errorf("second created-by stack should be the outermost parent, got %s", frameNames(stacks[1]))
+		}
+	})
+
+	t.Run("records are removed when the goroutine exits", func(t *testing.T) {
+		done := make(chan struct{})
+		Go(func() { close(done) })
+		<-done
+		// The record is removed after f returns; give the goroutine time to exit.
+		for i := 0; i < 1000 && liveSpawns.Load() != 0; i++ {
+			runtime.Gosched()
+		}
+		if n := liveSpawns.Load(); n != 0 {
+			t.Errorf("liveSpawns = %d, want 0", n)
+		}
+		if _, ok := spawnRecords.Load(goroutineID()); ok {
+			t.Error("current goroutine should not have a spawn record")
+		}
+	})
+
+	t.Run("only stacks inside Go end in runSpawned", func(t *testing.T) {
+		stop := make(chan struct{})
+		defer close(stop)
+		stackCh := make(chan []uintptr, 1)
+		Go(func() {
+			stackCh <- callers(1, DefaultMaxStackDepth)
+			<-stop
+		})
+		if !isSpawnedStack(<-stackCh) {
+			t.Error("stack captured inside Go should end in runSpawned")
+		}
+		stack := callers(1, DefaultMaxStackDepth)
+		if isSpawnedStack(stack) {
+			t.Error("stack captured outside Go should not end in runSpawned")
+		}
+		if stackSpawn(stack) != nil {
+			t.Error("goroutine not started by Go should have no spawn record")
+		}
+	})
+
+	t.Run("truncated stacks are not linked", func(t *testing.T) {
+		errCh := make(chan error, 1)
+		Go(func() {
+			errCh <- WithOptions(errors.New("worker failed"), Depth(1))
+		})
+		if stacks := createdByStacks(<-errCh); len(stacks) != 0 {
+			t.Errorf("found %d created-by stacks, want 0", len(stacks))
+		}
+	})
+
+	t.Run("runSpawned is hidden from stack traces", func(t *testing.T) {
+		err := spawnAndWrap()
+		if names := frameNames(err.(*withStack).error.(*withStack).StackFrames()); strings.Contains(names, "runSpawned") {
+			t.Errorf("worker stack should not show runSpawned, got %s", names)
+		}
+	})
+}
+
+// spawnWithContext records the stack with SpawnContext, then returns the result
+// of WithOptions with CreatedBy from a goroutine started with a go statement.
+//
+//go:noinline
+func spawnWithContext(ctx context.Context) error {
+	ctx = SpawnContext(ctx)
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- WithOptions(errors.New("worker failed"), CreatedBy(ctx))
+	}()
+	return <-errCh
+}
+
+func TestSpawnContext(t *testing.T) {
+	t.Run("CreatedBy links the recorded stack", func(t *testing.T) {
+		err := spawnWithContext(context.Background())
+
+		stacks := createdByStacks(err)
+		if len(stacks) != 1 {
+			t.Fatalf("found %d created-by stacks, want 1", len(stacks))
+		}
+		if stacks[0][0].Name != "spawnWithContext" {
+			t.Errorf("created-by stack should start at the caller of SpawnContext, got %s", frameNames(stacks[0]))
+		}
+	})
+
+	t.Run("nested contexts form a chain", func(t *testing.T) {
+		err := spawnWithContext(SpawnContext(context.Background()))
+
+		stacks := createdByStacks(err)
+		if len(stacks) != 2 {
+			t.Fatalf("found %d created-by stacks, want 2", len(stacks))
+		}
+		if stacks[0][0].Name != "spawnWithContext" || !strings.HasPrefix(stacks[1][0].Name, "TestSpawnContext") {
+			t.Errorf("created-by stacks should go from the nearest parent outwards, got %s and %s",
+				frameNames(stacks[0]), frameNames(stacks[1]))
+		}
+	})
+
+	t.Run("SpawnContext inside Go links the Go stack", func(t *testing.T) {
+		errCh := make(chan error, 1)
+		Go(func() {
+			errCh <- spawnWithContext(context.Background())
+		})
+		if stacks := createdByStacks(<-errCh); len(stacks) != 2 {
+			t.Errorf("found %d created-by stacks, want 2", len(stacks))
+		}
+	})
+
+	t.Run("CreatedBy without a recorded stack links nothing", func(t *testing.T) {
+		errCh := make(chan error, 1)
+		Go(func() {
+			errCh <- WithOptions(errors.New("worker failed"), CreatedBy(context.Background()))
+		})
+		if stacks := createdByStacks(<-errCh); len(stacks) != 0 {
+			t.Errorf("found %d created-by stacks, want 0", len(stacks))
+		}
+	})
+}
+
+// recurse calls f at the bottom of n nested calls.
+//
+//go:noinline
+func recurse(n int, f func()) {
+	if n == 0 {
+		f()
+		return
+	}
+	recurse(n-1, f)
+}
+
+func BenchmarkWithSpawn(b *testing.B) {
+	baseErr := errors.New("test error")
+	b.Run("no goroutines started by Go", func(b *testing.B) {
+		b.ReportAllocs()
+		for b.Loop() {
+			_ = With(baseErr)
+		}
+	})
+	b.Run("outside Go while Go runs", func(b *testing.B) {
+		stop := make(chan struct{})
+		defer close(stop)
+		started := make(chan struct{})
+		Go(func() {
+			close(started)
+			<-stop
+		})
+		<-started
+		b.ReportAllocs()
+		for b.Loop() {
+			_ = With(baseErr)
+		}
+	})
+	b.Run("deep stack outside Go while Go runs", func(b *testing.B) {
+		stop := make(chan struct{})
+		defer close(stop)
+		started := make(chan struct{})
+		Go(func() {
+			close(started)
+			<-stop
+		})
+		<-started
+		b.ReportAllocs()
+		recurse(DefaultMaxStackDepth*2, func() {
+			for b.Loop() {
+				_ = With(baseErr)
+			}
+		})
+	})
+	b.Run("inside Go", func(b *testing.B) {
+		done := make(chan struct{})
+		b.ReportAllocs()
+		Go(func() {
+			defer close(done)
+			for b.Loop() {
+				_ = With(baseErr)
+			}
+		})
+		<-done
+	})
+	b.Run("CreatedBy", func(b *testing.B) {
+		ctx := SpawnContext(context.Background())
+		b.ReportAllocs()
+		for b.Loop() {
+			_ = WithOptions(baseErr, CreatedBy(ctx))
+		}
+	})
+}
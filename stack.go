@@ -0,0 +1,70 @@
+package errstk
+
+import (
+	"fmt"
+	"io"
+)
+
+// StackTracer is implemented by errors that carry a stack trace as the program
+// counters returned by runtime.Callers. Errors created by With, Wrap, Recover and
+// the other functions of this package implement it, and so can errors of other
+// packages: With and Wrap do not add a second stack to a chain that contains a
+// StackTracer, and WalkStack, ErrorStack and the other formatting functions print
+// its stack. The method name matches the interface used by bugsnag-go.
+// Stacks of error types with other methods can be recognized with
+// DefaultStackExtractors.
+type StackTracer interface {
+	error
+	Callers() []uintptr
+}
+
+// Stack is a stack trace: the program counters of its frames, innermost first,
+// as returned by runtime.Callers.
+//
+// A Stack formats like runtime/debug.Stack with %s and %v, and also prints
+// source context (see DefaultSourceContextLines) with %+v.
+type Stack []uintptr
+
+// StackOf returns the stack of the first error in the chain of err that carries one,
+// as found by DefaultStackExtractors, or false if there is none. The chain is visited
+// in the order of WalkStack, so for errors handed off with Rethrow or Group this is
+// the stack where the error started; use WalkStack to visit every stack of the chain.
+func StackOf(err error) (Stack, bool) {
+	return findStack(err)
+}
+
+// HasStack reports whether any error in the chain of err carries a stack,
+// as found by DefaultStackExtractors.
+func HasStack(err error) bool {
+	_, ok := findStack(err)
+	return ok
+}
+
+// Frames returns the resolved stack frames, filtered by DefaultFrameFilter.
+func (s Stack) Frames() []StackFrame {
+	return stackFramesFromPC(s)
+}
+
+// String returns the stack formatted in the same way as runtime/debug.Stack.
+func (s Stack) String() string {
+	return string(formatStackFrames(s.Frames()))
+}
+
+// Format implements fmt.Formatter.
+//
+//   - %s, %v: the frames, formatted like runtime/debug.Stack
+//   - %+v: the frames with source context lines, as printed by ErrorStack
+//
+// Other verbs print nothing.
+func (s Stack) Format(f fmt.State, verb rune) {
+	switch verb {
+	case 'v':
+		if f.Flag('+') {
+			_, _ = io.WriteString(f, formatErrorStackFrames(s.Frames()))
+			return
+		}
+		fallthrough
+	case 's':
+		_, _ = io.WriteString(f, s.String())
+	}
+}
@@ -0,0 +1,115 @@
+package errstk
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// tracedError is a third-party error type carrying its own stack.
+type tracedError struct {
+	msg   string
+	stack []uintptr
+}
+
+func (e *tracedError) Error() string      { return e.msg }
+func (e *tracedError) Callers() []uintptr { return e.stack }
+
+//go:noinline
+func newTracedError(msg string) *tracedError {
+	return &tracedError{msg: msg, stack: callers(3, DefaultMaxStackDepth)}
+}
+
+func TestStackOf(t *testing.T) {
+	t.Run("no stack", func(t *testing.T) {
+		if _, ok := StackOf(errors.New("plain")); ok {
+			t.Error("StackOf should report no stack for a plain error")
+		}
+		if HasStack(errors.New("plain")) || HasStack(nil) {
+			t.Error("HasStack should be false for errors without a stack")
+		}
+	})
+
+	t.Run("errstk error", func(t *testing.T) {
+		err := fmt.Errorf("wrapped: %w", With(errors.New("test error")))
+		stack, ok := StackOf(err)
+		if !ok {
+			t.Fatal("StackOf should find the stack in the chain")
+		}
+		if !HasStack(err) {
+			t.Error("HasStack should be true")
+		}
+		frames := stack.Frames()
+		if len(frames) == 0 || frames[0].Name != "TestStackOf.func2" {
+			t.Errorf("first frame = %v, want TestStackOf.func2", frames)
+		}
+	})
+
+	t.Run("panic error", func(t *testing.T) {
+		err := func() (err error) {
+			defer Recover(&err)
+			panic("boom")
+		}()
+		if _, ok := StackOf(err); !ok {
+			t.Error("StackOf should find the stack of a PanicError")
+		}
+	})
+
+	t.Run("rethrown error returns the origin stack", func(t *testing.T) {
+		origin := With(errors.New("test error"))
+		stack, ok := StackOf(Rethrow(origin))
+		if !ok || !slices.Equal(stack, origin.(*withStack).Callers()) {
+			t.Errorf("StackOf should return the stack where the error started, got %v", stack)
+		}
+	})
+}
+
+func TestStackFormat(t *testing.T) {
+	setSourceContextLines(t, 1)
+	stack, _ := StackOf(With(errors.New("test error")))
+
+	if got, want := fmt.Sprintf("%v", stack), string(formatStackFrames(stack.Frames())); got != want {
+		t.Errorf("%%v = %q, want %q", got, want)
+	}
+	if got, want := fmt.Sprintf("%s", stack), stack.String(); got != want {
+		t.Errorf("%%s = %q, want %q", got, want)
+	}
+	if !strings.HasPrefix(stack.String(), "github.com/tomoemon/go-errstk.TestStackFormat()\n") {
+		t.Errorf("String() = %q, want the test function first", stack.String())
+	}
+	if got := fmt.Sprintf("%+v", stack); !strings.Contains(got, "> ") || !strings.Contains(got, "StackOf(With(") {
+		t.Errorf("%%+v = %q, want source context", got)
+	}
+}
+
+func TestThirdPartyStackTracer(t *testing.T) {
+	traced := newTracedError("third party")
+
+	t.Run("With does not add a second stack", func(t *testing.T) {
+		err := With(fmt.Errorf("context: %w", traced))
+		if _, ok := err.(*withStack); ok {
+			t.Error("With should not wrap an error whose chain has a StackTracer")
+		}
+		if stack, _ := StackOf(err); len(stack) != len(traced.stack) {
+			t.Error("StackOf should return the third-party stack")
+		}
+	})
+
+	t.Run("WalkStack and ErrorStack", func(t *testing.T) {
+		var messages []string
+		WalkStack(traced, func(err error, frames []StackFrame) {
+			messages = append(messages, err.Error())
+			if len(frames) == 0 || frames[0].Name != "TestThirdPartyStackTracer" {
+				t.Errorf("first frame = %v, want TestThirdPartyStackTracer", frames)
+			}
+		})
+		if len(messages) != 1 || messages[0] != "third party" {
+			t.Errorf("WalkStack visited %v, want [third party]", messages)
+		}
+		if got := ErrorStack(traced); !strings.Contains(got, "go-errstk.TestThirdPartyStackTracer()") {
+			t.Errorf("ErrorStack() = %q, want the third-party stack", got)
+		}
+	})
+}
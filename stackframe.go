@@ -7,10 +7,6 @@
 package errstk
 
 import (
-	"bufio"
-	"bytes"
-	"errors"
-	"os"
 	"runtime"
 	"strings"
 )
@@ -28,19 +24,36 @@ type StackFrame struct {
 	Package string
 	// The underlying ProgramCounter
 	ProgramCounter uintptr
+	// Inlined reports whether the compiler inlined this function into its caller
+	Inlined bool
+	// Collapsed is the number of standard library frames this summary frame
+	// replaces (see FrameFilter.CollapseStdlib), or zero for a regular frame
+	Collapsed int
 }
 
 // newStackFrame popoulates a stack frame object from the program counter.
+//
+// The program counter is resolved with runtime.CallersFrames, so a program
+// counter of an inlined call is attributed to the inlined function, with the
+// file and line of the call inside it. runtime.Callers emits one program
+// counter per logical frame, so inlined frames appear as separate StackFrames
+// with Inlined set to true.
 func newStackFrame(pc uintptr) (frame StackFrame) {
 	frame = StackFrame{ProgramCounter: pc}
-	if frame.Func() == nil {
+	if pc == 0 {
 		return
 	}
-	frame.Package, frame.Name = packageAndName(frame.Func())
-
-	// pc -1 because the program counters we use are usually return addresses,
-	// and we want to show the line that corresponds to the function call
-	frame.File, frame.LineNumber = frame.Func().FileLine(pc - 1)
+	// CallersFrames subtracts 1 from pc because the program counters we use are
+	// usually return addresses, and we want to show the line of the function call.
+	f, _ := runtime.CallersFrames([]uintptr{pc}).Next()
+	if f.Function == "" {
+		return
+	}
+	frame.Package, frame.Name = packageAndName(f.Function)
+	frame.File, frame.LineNumber = f.File, f.Line
+	// Func is nil for inlined frames, but Entry still refers to the real
+	// function they are inlined into.
+	frame.Inlined = f.Func == nil && f.Entry != 0
 	return
 }
 
@@ -52,6 +65,14 @@ func (frame *StackFrame) Func() *runtime.Func {
 	return runtime.FuncForPC(frame.ProgramCounter)
 }
 
+// FullName returns the package-qualified function name, e.g. "net/http.HandlerFunc.ServeHTTP".
+func (frame *StackFrame) FullName() string {
+	if frame.Package == "" {
+		return frame.Name
+	}
+	return frame.Package + "." + frame.Name
+}
+
 // String returns the stackframe formatted in the same way as go does
 // in runtime/debug.Stack()
 func (frame *StackFrame) String() string {
@@ -59,6 +80,7 @@ func (frame *StackFrame) String() string {
 }
 
 // SourceLine gets the line of code (from File and Line) of the original source if possible.
+// Source files are cached; see DefaultSourceCacheSize.
 func (frame *StackFrame) SourceLine() (string, error) {
 	source, err := frame.sourceLine()
 	if err != nil {
@@ -67,36 +89,22 @@ func (frame *StackFrame) SourceLine() (string, error) {
 	return source, err
 }
 
-func (frame *StackFrame) sourceLine() (result string, err error) {
+func (frame *StackFrame) sourceLine() (string, error) {
 	if frame.LineNumber <= 0 {
 		return "???", nil
 	}
 
-	file, err := os.Open(frame.File)
+	lines, err := frame.sourceLines()
 	if err != nil {
 		return "", err
 	}
-	defer func() {
-		err = errors.Join(err, file.Close())
-	}()
-
-	scanner := bufio.NewScanner(file)
-	currentLine := 1
-	for scanner.Scan() {
-		if currentLine == frame.LineNumber {
-			return string(bytes.Trim(scanner.Bytes(), " \t")), nil
-		}
-		currentLine++
-	}
-	if err := scanner.Err(); err != nil {
-		return "", err
+	if frame.LineNumber > len(lines) {
+		return "???", nil
 	}
-
-	return "???", nil
+	return strings.Trim(lines[frame.LineNumber-1], " \t"), nil
 }
 
-func packageAndName(fn *runtime.Func) (string, string) {
-	name := fn.Name()
+func packageAndName(name string) (string, string) {
 	pkg := ""
 
 	// The name includes the path name to the package, which is unnecessary
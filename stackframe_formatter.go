@@ -7,12 +7,21 @@ type stackFrameFormatter func(frame *StackFrame) string
 
 // defaultStackFrameFormatter returns the stack frame formatted in the same way as Go does
 // in runtime/debug.Stack().
+// Summary frames created by FrameFilter.CollapseStdlib are formatted as a single line.
 func defaultStackFrameFormatter(frame *StackFrame) string {
+	if frame.Collapsed > 0 {
+		// Format: ... 3 standard library frames
+		return formatCollapsedFrame(frame)
+	}
 	// Format: package.FunctionName()
 	//     file/path.go:123 +0xhex
-	name := frame.Name
-	if frame.Package != "" {
-		name = frame.Package + "." + frame.Name
+	return fmt.Sprintf("%s()\n\t%s:%d +0x%x\n", frame.FullName(), frame.File, frame.LineNumber, frame.ProgramCounter)
+}
+
+// formatCollapsedFrame returns the summary line of a collapsed run of standard library frames.
+func formatCollapsedFrame(frame *StackFrame) string {
+	if frame.Collapsed == 1 {
+		return "... 1 standard library frame\n"
 	}
-	return fmt.Sprintf("%s()\n\t%s:%d +0x%x\n", name, frame.File, frame.LineNumber, frame.ProgramCounter)
+	return fmt.Sprintf("... %d standard library frames\n", frame.Collapsed)
 }
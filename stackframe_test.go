@@ -0,0 +1,150 @@
+package errstk
+
+import (
+	"errors"
+	"os"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"testing"
+)
+
+// inlinedWith is small enough to be inlined into its callers,
+// so the frame of the With call inside it is an inlined frame.
+func inlinedWith(err error) error {
+	return With(err) // inlinedWithLine
+}
+
+//go:noinline
+func callInlinedWith(err error) error {
+	return inlinedWith(err) // callInlinedWithLine
+}
+
+// markerLine returns the number of the line in this file that ends with the
+// comment "// " + marker.
+func markerLine(t *testing.T, marker string) int {
+	t.Helper()
+	_, file, _, _ := runtime.Caller(0)
+	data, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatalf("reading %s: %v", file, err)
+	}
+	for i, line := range strings.Split(string(data), "\n") {
+		if strings.HasSuffix(line, "// "+marker) {
+			return i + 1
+		}
+	}
+	t.Fatalf("no line ends with // %s in %s", marker, file)
+	return 0
+}
+
+// inliningDisabled reports whether the test binary was built with -gcflags
+// that turn off inlining (-l) or optimizations (-N).
+func inliningDisabled() bool {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return false
+	}
+	for _, setting := range info.Settings {
+		if setting.Key != "-gcflags" {
+			continue
+		}
+		for _, flag := range strings.Fields(setting.Value) {
+			// Strip a package pattern such as "all=".
+			if i := strings.Index(flag, "="); i >= 0 {
+				flag = flag[i+1:]
+			}
+			if flag == "-l" || flag == "-N" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func TestStackFramesInlining(t *testing.T) {
+	if testing.CoverMode() != "" {
+		t.Skip("coverage instrumentation may prevent inlining")
+	}
+	if inliningDisabled() {
+		t.Skip("inlining is disabled by -gcflags")
+	}
+
+	err := callInlinedWith(errors.New("test error"))
+	frames := err.(*withStack).StackFrames()
+	if len(frames) < 2 {
+		t.Fatalf("expected at least 2 frames, got %d", len(frames))
+	}
+
+	inlined := frames[0]
+	if inlined.Name != "inlinedWith" {
+		t.Errorf("frame 0 Name = %q, want %q", inlined.Name, "inlinedWith")
+	}
+	if want := markerLine(t, "inlinedWithLine"); inlined.LineNumber != want {
+		t.Errorf("frame 0 LineNumber = %d, want %d", inlined.LineNumber, want)
+	}
+	if !inlined.Inlined {
+		t.Error("frame 0 should be marked as inlined")
+	}
+
+	caller := frames[1]
+	if caller.Name != "callInlinedWith" {
+		t.Errorf("frame 1 Name = %q, want %q", caller.Name, "callInlinedWith")
+	}
+	if want := markerLine(t, "callInlinedWithLine"); caller.LineNumber != want {
+		t.Errorf("frame 1 LineNumber = %d, want %d", caller.LineNumber, want)
+	}
+	if caller.Inlined {
+		t.Error("frame 1 should not be marked as inlined")
+	}
+	if caller.Package != "github.com/tomoemon/go-errstk" {
+		t.Errorf("frame 1 Package = %q, want %q", caller.Package, "github.com/tomoemon/go-errstk")
+	}
+}
+
+func TestStackFramesMatchCallersFrames(t *testing.T) {
+	err := callInlinedWith(errors.New("test error")).(*withStack)
+
+	got := err.StackFrames()
+	iter := runtime.CallersFrames(err.Callers())
+	for i := 0; ; i++ {
+		want, more := iter.Next()
+		if i >= len(got) {
+			t.Fatalf("StackFrames returned %d frames, runtime.CallersFrames returned more", len(got))
+		}
+		pkg, name := packageAndName(want.Function)
+		if got[i].Package != pkg || got[i].Name != name || got[i].File != want.File || got[i].LineNumber != want.Line {
+			t.Errorf("frame %d = %s.%s %s:%d, want %s.%s %s:%d", i,
+				got[i].Package, got[i].Name, got[i].File, got[i].LineNumber,
+				pkg, name, want.File, want.Line)
+		}
+		if !more {
+			if i != len(got)-1 {
+				t.Errorf("StackFrames returned %d frames, runtime.CallersFrames returned %d", len(got), i+1)
+			}
+			break
+		}
+	}
+}
+
+func TestPackageAndName(t *testing.T) {
+	tests := []struct {
+		input   string
+		wantPkg string
+		want    string
+	}{
+		{"main.main", "main", "main"},
+		{"runtime.goexit", "runtime", "goexit"},
+		{"net/http.HandlerFunc.ServeHTTP", "net/http", "HandlerFunc.ServeHTTP"},
+		{"github.com/tomoemon/go-errstk.(*withStack).Format", "github.com/tomoemon/go-errstk", "(*withStack).Format"},
+		{"github.com/tomoemon/go-errstk.TestWith.func1", "github.com/tomoemon/go-errstk", "TestWith.func1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			pkg, name := packageAndName(tt.input)
+			if pkg != tt.wantPkg || name != tt.want {
+				t.Errorf("packageAndName(%q) = (%q, %q), want (%q, %q)", tt.input, pkg, name, tt.wantPkg, tt.want)
+			}
+		})
+	}
+}
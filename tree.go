@@ -0,0 +1,83 @@
+package errstk
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// ErrorTree returns the whole error graph of err rendered as an indented tree.
+// Each node shows the concrete Go type and message of one error in the chain.
+// fmt.Errorf wrappers have a single child, and the branches of errors.Join are
+// drawn as siblings. Nodes with a stack trace list their fields and frames below
+// the message, formatted with DefaultStackFrameFormatter.
+//
+// As with NewJSONError, an errstk stack wrapper is merged with the error it wraps.
+// Stacks attached by Rethrow, Group and Go are drawn as separate nodes whose
+// frames are headed by their label, such as "rethrown at:".
+// ErrorTree is also available as the %#v verb on errors returned by errstk.
+// Returns an empty string if err is nil.
+//
+// Example output:
+//
+//	*fmt.wrapError: handler: error 1
+//	│   error 2
+//	└── *errors.joinError: error 1
+//	    │   error 2
+//	    ├── *errors.errorString: error 1
+//	    │       main.load()
+//	    │       	/path/to/main.go:10 +0x1234567
+//	    └── *errors.errorString: error 2
+//	            main.save()
+//	            	/path/to/main.go:20 +0x1234567
+func ErrorTree(err error) string {
+	if err == nil {
+		return ""
+	}
+	var b strings.Builder
+	writeTreeNode(&b, NewJSONError(err), "", "")
+	return b.String()
+}
+
+// writeTreeNode writes node and its children. prefix is written before the first
+// line of the node, and indent before every other line belonging to it.
+func writeTreeNode(b *strings.Builder, node *JSONError, prefix, indent string) {
+	bodyIndent := indent + "    "
+	if len(node.Children) > 0 {
+		bodyIndent = indent + "│   "
+	}
+
+	lines := strings.Split(node.Message, "\n")
+	fmt.Fprintf(b, "%s%s: %s\n", prefix, node.Type, lines[0])
+	for _, line := range lines[1:] {
+		b.WriteString(bodyIndent + line + "\n")
+	}
+
+	var body strings.Builder
+	if len(node.Fields) > 0 {
+		fields := make([]Field, 0, len(node.Fields))
+		for _, key := range slices.Sorted(maps.Keys(node.Fields)) {
+			fields = append(fields, Field{Key: key, Value: node.Fields[key]})
+		}
+		body.WriteString(formatFields(fields) + "\n")
+	}
+	if node.Label != "" {
+		body.WriteString(node.Label + ":\n")
+	}
+	for _, f := range node.Frames {
+		frame := f.stackFrame()
+		body.WriteString(frame.String())
+	}
+	for line := range strings.Lines(body.String()) {
+		b.WriteString(bodyIndent + line)
+	}
+
+	for i, child := range node.Children {
+		if i == len(node.Children)-1 {
+			writeTreeNode(b, child, indent+"└── ", indent+"    ")
+		} else {
+			writeTreeNode(b, child, indent+"├── ", indent+"│   ")
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package errstk
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestErrorTree(t *testing.T) {
+	t.Run("nil error", func(t *testing.T) {
+		if got := ErrorTree(nil); got != "" {
+			t.Errorf("ErrorTree(nil) = %q, want empty string", got)
+		}
+	})
+
+	t.Run("error without stack", func(t *testing.T) {
+		err := fmt.Errorf("outer: %w", errors.New("inner"))
+
+		want := "*fmt.wrapError: outer: inner\n" +
+			"└── *errors.errorString: inner\n"
+		if got := ErrorTree(err); got != want {
+			t.Errorf("ErrorTree() =\n%s\nwant:\n%s", got, want)
+		}
+	})
+
+	t.Run("errors.Join branches are siblings", func(t *testing.T) {
+		err := fmt.Errorf("handler: %w", errors.Join(
+			With(errors.New("error 1"), "user_id", 42),
+			fmt.Errorf("save: %w", With(errors.New("error 2"))),
+		))
+
+		tree := ErrorTree(err)
+		lines := strings.Split(tree, "\n")
+		for _, prefix := range []string{
+			"*fmt.wrapError: handler: error 1",
+			"│   save: error 2",
+			"└── *errors.joinError: error 1",
+			"    │   save: error 2",
+			"    ├── *errors.errorString: error 1",
+			"    │       fields: user_id=42",
+			"    │       github.com/tomoemon/go-errstk.TestErrorTree.func3()",
+		} {
+			if !slices.ContainsFunc(lines, func(line string) bool { return strings.HasPrefix(line, prefix) }) {
+				t.Errorf("ErrorTree should contain line %q, got:\n%s", prefix, tree)
+			}
+		}
+		if !strings.Contains(tree, "    └── *fmt.wrapError: save: error 2\n        └── *errors.errorString: error 2\n                github.com/tomoemon/go-errstk.TestErrorTree.func3()\n") {
+			t.Errorf("last branch should be drawn with └──, got:\n%s", tree)
+		}
+		if strings.Count(tree, "tree_test.go") < 2 {
+			t.Errorf("ErrorTree should show the frames of both stacks, got:\n%s", tree)
+		}
+	})
+
+	t.Run("hand-off stacks are labeled nodes", func(t *testing.T) {
+		err := Rethrow(fmt.Errorf("ctx: %w", With(errors.New("base"))))
+
+		tree := ErrorTree(err)
+		if !strings.HasPrefix(tree, "*errstk.withStack: ctx: base\n│   rethrown at:\n│   github.com/tomoemon/go-errstk.TestErrorTree.func4()\n") {
+			t.Errorf("rethrown stack should be drawn as a labeled node, got:\n%s", tree)
+		}
+		if !strings.Contains(tree, "└── *fmt.wrapError: ctx: base\n    └── *errors.errorString: base\n") {
+			t.Errorf("wrapped error should not carry the rethrown stack, got:\n%s", tree)
+		}
+	})
+
+	t.Run("%#v renders the tree", func(t *testing.T) {
+		err := With(errors.New("test error"))
+		if got, want := fmt.Sprintf("%#v", err), ErrorTree(err); got != want {
+			t.Errorf("%%#v = %q, want %q", got, want)
+		}
+
+		wrapped := With(err, "user_id", 42)
+		if got, want := fmt.Sprintf("%#v", wrapped), ErrorTree(wrapped); got != want {
+			t.Errorf("%%#v on fields wrapper = %q, want %q", got, want)
+		}
+		if !strings.HasPrefix(fmt.Sprintf("%#v", wrapped), "*errors.errorString: test error\n    fields: user_id=42\n") {
+			t.Errorf("%%#v should show the merged node, got:\n%#v", wrapped)
+		}
+	})
+}